@@ -69,6 +69,20 @@ func RequestSigning(id, key string) ClientOpt {
 	}
 }
 
+// OtelTracing traces requests with OpenTelemetry instead of opentracing. It's
+// the default when request.DefaultOtelTracing is set, like by svc.InitAll.
+func OtelTracing(c *Client) {
+	c.Handlers.Send.Swap("TracedSender", request.WithOtelTracing(request.BaseSender))
+}
+
+// Metrics reports metrics about the requests sent, tagged with the
+// ServiceName of the client, see request.MetricsReporter. Name the operation
+// of a request with metricsclient.WithOperation on its context.
+func Metrics(c *Client) {
+	c.Handlers.Send.Prepend(request.MetricsStarter)
+	c.Handlers.Send.Append(request.MetricsReporter)
+}
+
 // DebugLogging adds logging of the enitre request and response.
 func DebugLogging(c *Client) {
 	c.Handlers.Send.Prepend(request.RequestLogger)
@@ -10,6 +10,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/remind101/pkg/client"
 	"github.com/remind101/pkg/client/metadata"
+	"github.com/remind101/pkg/metrics/metricsclient"
+	"github.com/remind101/pkg/metrics/metricstest"
 )
 
 type mathClient struct {
@@ -61,3 +63,28 @@ func TestClient(t *testing.T) {
 		t.Errorf("got %d; expected %d", got, want)
 	}
 }
+
+func TestClient_Metrics(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	c := client.New(metadata.ClientInfo{ServiceName: "Users", Endpoint: s.URL}, client.Metrics)
+	ctx := metricsclient.WithOperation(context.Background(), "GetUser")
+	c.NewRequest(ctx, "GET", "/users/1", nil, nil).Send()
+
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricRequestTime, map[string]string{
+		"service":      "Users",
+		"operation":    "GetUser",
+		"status":       "404",
+		"status_class": "4xx",
+		"error":        metricsclient.ErrorNone,
+	})
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricConnectTime, map[string]string{
+		"service": "Users",
+	})
+}
@@ -11,11 +11,19 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/99designs/httpsignatures-go"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/metrics/metricsclient"
+	"github.com/remind101/pkg/tracing/tracecontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
 
@@ -31,13 +39,22 @@ type Handlers struct {
 	Complete         HandlerList
 }
 
+// DefaultOtelTracing makes DefaultHandlers trace requests with
+// WithOtelTracing instead of WithTracing. svc.InitAll sets it when it installs
+// an OpenTelemetry tracer.
+var DefaultOtelTracing bool
+
 // DefaultHandlers defines a basic request configuration that assumes JSON
 // requests and responses.
 func DefaultHandlers() Handlers {
+	send := WithTracing(BaseSender)
+	if DefaultOtelTracing {
+		send = WithOtelTracing(BaseSender)
+	}
 	return Handlers{
-		Build:            NewHandlerList(JSONBuilder),
+		Build:            NewHandlerList(JSONBuilder, RequestIDForwarder),
 		Sign:             NewHandlerList(),
-		Send:             NewHandlerList(WithTracing(BaseSender)),
+		Send:             NewHandlerList(send),
 		ValidateResponse: NewHandlerList(),
 		Decode:           NewHandlerList(JSONDecoder),
 		DecodeError:      NewHandlerList(),
@@ -87,6 +104,18 @@ func (hl *HandlerList) Prepend(h Handler) {
 	hl.list = append([]Handler{h}, hl.list...)
 }
 
+// Swap replaces the first handler with the given name with h. It reports
+// whether a handler was replaced.
+func (hl *HandlerList) Swap(name string, h Handler) bool {
+	for i, old := range hl.list {
+		if old.Name == name {
+			hl.list[i] = h
+			return true
+		}
+	}
+	return false
+}
+
 // Clear truncates a handler list.
 func (hl *HandlerList) Clear() {
 	hl.list = []Handler{}
@@ -238,6 +267,18 @@ func HeadersFromContext(headers ...string) Handler {
 	}
 }
 
+// RequestIDForwarder adds the request id from the request context to the
+// X-Request-Id header, so it is forwarded to the downstream service.
+var RequestIDForwarder = Handler{
+	Name: "RequestIDForwarder",
+	Fn: func(r *Request) {
+		requestID := httpx.RequestID(r.HTTPRequest.Context())
+		if requestID != "" && r.HTTPRequest.Header.Get(httpx.RequestIDHeader) == "" {
+			r.HTTPRequest.Header.Set(httpx.RequestIDHeader, requestID)
+		}
+	},
+}
+
 // RequestLogger dumps the entire request to stdout.
 var RequestLogger = Handler{
 	Name: "RequestLogger",
@@ -264,18 +305,53 @@ var ResponseLogger = Handler{
 	},
 }
 
+// MetricsStarter starts tracing a request, for MetricsReporter. It should be first in the Send handlers.
+var MetricsStarter = Handler{
+	Name: "MetricsStarter",
+	Fn: func(r *Request) {
+		ctx, _ := metricsclient.WithTrace(r.HTTPRequest.Context())
+		r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+	},
+}
+
+// MetricsReporter reports metrics about a request sent since MetricsStarter,
+// tagged with the ServiceName of the client, see metricsclient.Report. It
+// should be last in the Send handlers.
+var MetricsReporter = Handler{
+	Name: "MetricsReporter",
+	Fn: func(r *Request) {
+		ctx := r.HTTPRequest.Context()
+		trace := metricsclient.TraceFromContext(ctx)
+		if trace == nil {
+			return
+		}
+		metricsclient.Report(ctx, metricsclient.Request{
+			Service:  r.ClientInfo.ServiceName,
+			Method:   r.HTTPRequest.Method,
+			Response: r.HTTPResponse,
+			Err:      r.Error,
+			Duration: time.Since(trace.Timings().Start),
+			Trace:    trace,
+		})
+	},
+}
+
 // WithTracing returns a Send Handler that wraps another Send Handler in a trace
-// span.
+// span. The span is propagated with the tracer's headers as well as the W3C
+// traceparent, tracestate and baggage headers. The span is tagged with the
+// timings of the request, see metricsclient.Timings.
 func WithTracing(h Handler) Handler {
 	return Handler{
 		Name: "TracedSender",
 		Fn: func(r *Request) {
 			span, ctx := opentracing.StartSpanFromContext(r.HTTPRequest.Context(), "client.request")
+			ctx, trace := metricsclient.WithTrace(ctx)
 			opentracing.GlobalTracer().Inject(
 				span.Context(),
 				opentracing.HTTPHeaders,
 				opentracing.HTTPHeadersCarrier(r.HTTPRequest.Header),
 			)
+			tracecontext.Inject(ctx, r.HTTPRequest.Header)
 			defer span.Finish()
 			r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
 
@@ -291,9 +367,76 @@ func WithTracing(h Handler) Handler {
 				span.SetTag("http.status_code", r.HTTPResponse.StatusCode)
 			}
 
+			if timings := trace.Timings(); timings.Attempts > 0 {
+				for k, v := range timings.Tags() {
+					span.SetTag(k, v)
+				}
+			}
+
 			if r.Error != nil {
 				span.SetTag(ext.Error, r.Error)
 			}
 		},
 	}
 }
+
+// WithOtelTracing is the OpenTelemetry counterpart of WithTracing. The span is
+// created with the global TracerProvider and propagated with the global
+// TextMapPropagator.
+func WithOtelTracing(h Handler) Handler {
+	return Handler{
+		Name: "OtelTracedSender",
+		Fn: func(r *Request) {
+			ctx, span := otel.Tracer(otelTracerName).Start(r.HTTPRequest.Context(), "client.request",
+				trace.WithSpanKind(trace.SpanKindClient))
+			defer span.End()
+			ctx, trace := metricsclient.WithTrace(ctx)
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.HTTPRequest.Header))
+			tracecontext.Inject(ctx, r.HTTPRequest.Header)
+			r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+
+			span.SetAttributes(
+				attribute.String(ext.ResourceName, r.ClientInfo.ServiceName),
+				attribute.String("http.method", r.HTTPRequest.Method),
+				attribute.String("http.url", r.HTTPRequest.URL.Hostname()+r.HTTPRequest.URL.EscapedPath()),
+				attribute.String("out.host", r.HTTPRequest.URL.Hostname()),
+				attribute.String("out.port", r.HTTPRequest.URL.Port()),
+			)
+
+			h.Fn(r)
+
+			if r.HTTPResponse != nil {
+				span.SetAttributes(attribute.Int("http.status_code", r.HTTPResponse.StatusCode))
+			}
+
+			if timings := trace.Timings(); timings.Attempts > 0 {
+				span.SetAttributes(timingAttributes(timings)...)
+			}
+
+			if r.Error != nil {
+				span.RecordError(r.Error)
+				span.SetStatus(codes.Error, r.Error.Error())
+			}
+		},
+	}
+}
+
+// timingAttributes returns the span tags of the timings as attributes.
+func timingAttributes(t metricsclient.Timings) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for k, v := range t.Tags() {
+		switch v := v.(type) {
+		case int:
+			attrs = append(attrs, attribute.Int(k, v))
+		case float64:
+			attrs = append(attrs, attribute.Float64(k, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(k, v))
+		}
+	}
+	return attrs
+}
+
+// otelTracerName is the instrumentation name used for spans created by this
+// package.
+const otelTracerName = "github.com/remind101/pkg/client/request"
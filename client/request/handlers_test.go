@@ -9,6 +9,7 @@ import (
 	httpsignatures "github.com/99designs/httpsignatures-go"
 	"github.com/remind101/pkg/client/request"
 	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/tracing/oteltest"
 )
 
 // Test Basic Auth
@@ -63,6 +64,19 @@ func TestForwardingHeaders(t *testing.T) {
 	}))
 }
 
+// Test Forwarding Request ID
+func TestRequestIDForwarder(t *testing.T) {
+	r := newTestRequest("GET", "/", nil, nil)
+	ctx := httpx.WithRequestID(r.HTTPRequest.Context(), "abc")
+	r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+
+	sendRequest(r, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("X-Request-Id"), "abc"; got != want {
+			t.Errorf("got %s; expected %s", got, want)
+		}
+	}))
+}
+
 // Test Request Signing
 func TestRequestSinging(t *testing.T) {
 	r := newTestRequest("GET", "/", nil, nil)
@@ -86,6 +100,46 @@ func TestDebugLogging(t *testing.T) {
 	}))
 }
 
+func TestOtelTracingTimings(t *testing.T) {
+	exporter, restore := oteltest.Install()
+	defer restore()
+
+	r := newTestRequest("GET", "/", nil, nil)
+	r.Handlers.Send.Swap("TracedSender", request.WithOtelTracing(request.BaseSender))
+	sendRequest(r, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+	}))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 finished span, got %d", len(spans))
+	}
+	if got, want := oteltest.Attribute(spans[0], "http.attempts").AsInt64(), int64(1); got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if got := oteltest.Attribute(spans[0], "http.connect_ms").AsFloat64(); got <= 0 {
+		t.Errorf("expected the connect time to be recorded, got %f", got)
+	}
+	if got, want := oteltest.Attribute(spans[0], "http.conn_reused").AsBool(), false; got != want {
+		t.Errorf("got %t; expected %t", got, want)
+	}
+}
+
+func TestDefaultOtelTracing(t *testing.T) {
+	exporter, restore := oteltest.Install()
+	defer restore()
+
+	request.DefaultOtelTracing = true
+	defer func() { request.DefaultOtelTracing = false }()
+
+	r := newTestRequest("GET", "/", nil, nil)
+	sendRequest(r, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+	}))
+
+	if got, want := len(exporter.GetSpans()), 1; got != want {
+		t.Fatalf("got %d finished spans; expected %d", got, want)
+	}
+}
+
 func TestInvalidJSON(t *testing.T) {
 	invalidJSONs := [][]byte{
 		[]byte("{\"value\": \"hello\"}{\"value\": \"world\"}"),
@@ -0,0 +1,465 @@
+// Package config populates configuration structs from environment variables,
+// .env files and command line flags, as described by struct tags.
+//
+// Usage:
+//
+//	type Config struct {
+//		Port     int           `env:"PORT" default:"8080"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"5s" flag:"timeout"`
+//		Upstream *url.URL      `env:"UPSTREAM_URL" required:"true"`
+//		Hosts    []string      `env:"HOSTS"`
+//		APIKey   string        `env:"API_KEY" secret:"true"`
+//		DB       DBConfig      `prefix:"DB_"`
+//	}
+//
+//	var c Config
+//	if err := config.Load(&c, config.WithEnvFile(".env")); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Supported tags:
+//
+//	env       name of the environment variable
+//	default   value used when no source sets the field
+//	required  "true" to fail when no source sets the field
+//	secret    "true" to mask the value in Dump
+//	flag      name of a command line flag, see WithFlags
+//	desc      usage of the flag
+//	sep       separator for slices, "," by default
+//	prefix    prefix for the environment variables of a nested struct
+//
+// Values are taken, from lowest to highest precedence, from the default tag,
+// .env files, the environment and flags.
+package config
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is returned when a field can't be populated.
+type FieldError struct {
+	// Field is the path of the struct field, like "DB.Host".
+	Field string
+	// Key is the environment variable of the field, if any.
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("config: %s (%s): %v", e.Field, e.Key, e.Err)
+	}
+	return fmt.Sprintf("config: %s: %v", e.Field, e.Err)
+}
+
+// Errors holds every error that occurred while loading a struct.
+type Errors []error
+
+// Error implements the error interface, with one error per line.
+func (e Errors) Error() string {
+	m := make([]string, len(e))
+	for i, err := range e {
+		m[i] = err.Error()
+	}
+	return strings.Join(m, "\n")
+}
+
+// ErrRequired is the error of a FieldError for a required field that wasn't
+// set.
+var ErrRequired = fmt.Errorf("required but not set")
+
+// Option configures Load.
+type Option func(*loader) error
+
+// WithLookup sets the function used to look up environment variables. The
+// default is os.LookupEnv.
+func WithLookup(lookup func(string) (string, bool)) Option {
+	return func(l *loader) error {
+		l.lookup = lookup
+		return nil
+	}
+}
+
+// WithPrefix prepends prefix to the names of all environment variables.
+func WithPrefix(prefix string) Option {
+	return func(l *loader) error {
+		l.prefix = prefix
+		return nil
+	}
+}
+
+// WithEnvFile reads variables from .env files, which take precedence over
+// defaults, but not over the environment. Files that don't exist are skipped.
+// When a variable is set in several files, the first one wins.
+func WithEnvFile(paths ...string) Option {
+	return func(l *loader) error {
+		for _, path := range paths {
+			b, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			vars, err := ParseEnvFile(string(b))
+			if err != nil {
+				return fmt.Errorf("config: %s: %v", path, err)
+			}
+			for k, v := range vars {
+				if _, ok := l.file[k]; !ok {
+					l.file[k] = v
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// WithFlags defines a flag on fs for every field with a flag tag, and parses
+// args with it. Flags that are set take precedence over every other source.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *loader) error {
+		l.flags = fs
+		l.args = args
+		return nil
+	}
+}
+
+type loader struct {
+	lookup func(string) (string, bool)
+	prefix string
+	file   map[string]string
+	flags  *flag.FlagSet
+	args   []string
+}
+
+// Load populates v, which must be a pointer to a struct, as described by its
+// struct tags. All the errors are reported at once, as Errors.
+func Load(v interface{}, opts ...Option) error {
+	l := &loader{
+		lookup: os.LookupEnv,
+		file:   make(map[string]string),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return err
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load expects a pointer to a struct, got %T", v)
+	}
+
+	fields := collect(rv.Elem(), "", l.prefix)
+
+	// Flags are parsed first so that the ones that are set can be told apart.
+	flagValues := make(map[string]*flagValue)
+	if l.flags != nil {
+		for _, f := range fields {
+			if f.flag != "" {
+				fv := &flagValue{s: f.def, isBool: isBool(f.value)}
+				l.flags.Var(fv, f.flag, f.desc)
+				flagValues[f.flag] = fv
+			}
+		}
+		if err := l.flags.Parse(l.args); err != nil {
+			return err
+		}
+	}
+	setFlags := make(map[string]bool)
+	if l.flags != nil {
+		l.flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	}
+
+	var errs Errors
+	for _, f := range fields {
+		raw, ok := "", false
+		if f.flag != "" && setFlags[f.flag] {
+			raw, ok = flagValues[f.flag].s, true
+		} else if f.key != "" {
+			if raw, ok = l.lookup(f.key); !ok {
+				raw, ok = l.file[f.key]
+			}
+		}
+		if !ok && f.hasDef {
+			raw, ok = f.def, true
+		}
+
+		if !ok {
+			if f.required {
+				errs = append(errs, &FieldError{Field: f.path, Key: f.key, Err: ErrRequired})
+			}
+			continue
+		}
+
+		if err := setValue(f.value, raw, f.sep); err != nil {
+			errs = append(errs, &FieldError{Field: f.path, Key: f.key, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Dump returns the fields of v, a struct or a pointer to one, as sorted
+// KEY=value lines. The values of secret fields are masked.
+func Dump(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		// Copied, so that the TextMarshalers with pointer receivers are
+		// found.
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p
+	}
+	rv = rv.Elem()
+
+	var lines []string
+	for _, f := range collect(rv, "", "") {
+		name := f.key
+		if name == "" {
+			name = f.path
+		}
+
+		value := formatValue(f.value, f.sep)
+		if f.secret && value != "" {
+			value = "****"
+		}
+		lines = append(lines, name+"="+value)
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+// flagValue is the flag.Value of a field. The raw value is parsed with the
+// other sources, and bool fields are registered as bool flags, so that they
+// can be set with -name alone.
+type flagValue struct {
+	s      string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.s
+}
+
+func (v *flagValue) Set(s string) error {
+	v.s = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// isBool reports whether v holds a bool, or a pointer to one, that isn't an
+// encoding.TextUnmarshaler.
+func isBool(v reflect.Value) bool {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// field is a struct field that can be populated.
+type field struct {
+	value    reflect.Value
+	path     string
+	key      string
+	flag     string
+	desc     string
+	def      string
+	hasDef   bool
+	required bool
+	secret   bool
+	sep      string
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// collect returns the fields of the struct rv, recursing into nested structs.
+func collect(rv reflect.Value, path, prefix string) []field {
+	var fields []field
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+
+		fv := rv.Field(i)
+		fpath := path + sf.Name
+
+		if isNested(sf.Type) {
+			fields = append(fields, collect(fv, fpath+".", prefix+sf.Tag.Get("prefix"))...)
+			continue
+		}
+
+		f := field{
+			value:    fv,
+			path:     fpath,
+			flag:     sf.Tag.Get("flag"),
+			desc:     sf.Tag.Get("desc"),
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			sep:      sf.Tag.Get("sep"),
+		}
+		if env := sf.Tag.Get("env"); env != "" {
+			f.key = prefix + env
+		}
+		f.def, f.hasDef = sf.Tag.Lookup("default")
+		if f.sep == "" {
+			f.sep = ","
+		}
+
+		if f.key == "" && f.flag == "" && !f.hasDef {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// isNested reports whether a field of type t is a struct whose fields should
+// be populated individually.
+func isNested(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == urlType {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue parses raw into v.
+func setValue(v reflect.Value, raw, sep string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(raw))
+		}
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), raw, sep); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.Struct:
+		if v.Type() != urlType {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" && u.Opaque == "" && u.Path == "" {
+			return fmt.Errorf("invalid url %q", raw)
+		}
+		v.Set(reflect.ValueOf(*u))
+	case reflect.Slice:
+		var parts []string
+		if raw != "" {
+			parts = strings.Split(raw, sep)
+		}
+		s := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(s.Index(i), strings.TrimSpace(part), sep); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// formatValue formats v the way setValue parses it.
+func formatValue(v reflect.Value, sep string) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			b, _ := m.MarshalText()
+			return string(b)
+		}
+	}
+
+	switch {
+	case v.Type() == urlType:
+		u := v.Interface().(url.URL)
+		return u.Redacted()
+	case v.Kind() == reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i), sep)
+		}
+		return strings.Join(parts, sep)
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
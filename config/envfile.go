@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseEnvFile parses the content of a .env file. Each line holds a KEY=value
+// pair, optionally preceded by "export". Blank lines and lines starting with
+// # are ignored. Values can be single quoted, taken literally, or double
+// quoted, in which case escape sequences like \n are interpreted. Unquoted
+// values end at the first " #".
+func ParseEnvFile(s string) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", i+1)
+		}
+
+		v, err := parseEnvValue(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		vars[k] = v
+	}
+
+	return vars, nil
+}
+
+func parseEnvValue(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+
+	switch v[0] {
+	case '\'':
+		end := strings.IndexByte(v[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return v[1 : end+1], nil
+	case '"':
+		prefix, err := strconv.QuotedPrefix(v)
+		if err != nil {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return strconv.Unquote(prefix)
+	}
+
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
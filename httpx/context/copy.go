@@ -6,7 +6,9 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/remind101/pkg/httpx"
 	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
 	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/tracing/tracecontext"
 )
 
 // Copy copies common httpx values injected into a request context to another
@@ -32,9 +34,19 @@ func CopyToContext(target, source context.Context) context.Context {
 		target = reporter.WithReporter(target, r)
 	}
 
+	// Copy metric tags
+	if tags := metrics.TagsFromContext(source); tags != nil {
+		target = metrics.WithTags(target, tags)
+	}
+
 	// Copy request id
 	target = httpx.WithRequestID(target, httpx.RequestID(source))
 
+	// Copy W3C trace context and baggage
+	if sc, ok := tracecontext.FromContext(source); ok {
+		target = tracecontext.WithSpanContext(target, sc)
+	}
+
 	// Copy trace span
 	if span := opentracing.SpanFromContext(source); span != nil {
 		target = opentracing.ContextWithSpan(target, span)
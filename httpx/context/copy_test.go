@@ -8,7 +8,9 @@ import (
 	"github.com/remind101/pkg/httpx"
 	httpxcontext "github.com/remind101/pkg/httpx/context"
 	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
 	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/tracing/tracecontext"
 )
 
 func TestCopy(t *testing.T) {
@@ -19,6 +21,8 @@ func TestCopy(t *testing.T) {
 	ctx = logger.WithLogger(ctx, l)
 	ctx = reporter.WithReporter(ctx, r)
 	ctx = httpx.WithRequestID(ctx, "abc")
+	ctx = metrics.WithTags(ctx, map[string]string{"tier": "gold"})
+	ctx = tracecontext.WithBaggageItem(ctx, "tier", "gold")
 	_, ctx = opentracing.StartSpanFromContext(ctx, "test.span")
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -41,6 +45,14 @@ func TestCopy(t *testing.T) {
 		t.Error("expected reporter in context")
 	}
 
+	if got, want := metrics.TagsFromContext(cc)["tier"], "gold"; got != want {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+
+	if got, want := tracecontext.BaggageItem(cc, "tier"), "gold"; got != want {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+
 	if span := opentracing.SpanFromContext(cc); span == nil {
 		t.Error("expected span in context")
 	}
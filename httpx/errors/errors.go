@@ -21,24 +21,29 @@ package errors
 
 import (
 	"context"
+	gerrors "errors"
 	"fmt"
 	"net/http"
 
 	"github.com/pkg/errors"
+	"github.com/remind101/pkg/redact"
 )
 
 // MaxFrames is the default maximum number of lines to show from the stack trace.
 var MaxFrames = 1024
 
-// WithInfo adds contextual information to the info object in the context.
+// WithInfo adds contextual information to the info object in the context. The
+// value is redacted with redact.Default, so that it can be reported.
 func WithInfo(ctx context.Context, key string, value interface{}) context.Context {
 	ctx = withInfo(ctx)
 	i, _ := infoFromContext(ctx)
-	i.data[key] = value
+	i.data[key] = redact.Default.Value(key, value)
 	return ctx
 }
 
-// WithRequest adds information from an http.Request to the info object in the context.
+// WithRequest adds information from an http.Request to the info object in the
+// context. Sensitive headers and form values are removed, and the others are
+// scrubbed with redact.Default.
 func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	ctx = withInfo(ctx)
 	i, _ := infoFromContext(ctx)
@@ -47,21 +52,29 @@ func WithRequest(ctx context.Context, req *http.Request) context.Context {
 }
 
 // Recover wraps the return value of recover() to capture a panic stack correctly.
+// The returned error is reported as a panic by IsPanic.
 func Recover(ctx context.Context, v interface{}) (e error) {
 	switch err := v.(type) {
 	case nil:
-		e = nil
+		return nil
 	case *Error:
+		err.panicked = true
 		e = err
 	case error:
-		e = New(ctx, err, 0)
+		e = New(ctx, err, 0).markPanicked()
 	default:
-		e = New(ctx, fmt.Errorf("%v", err), 0)
+		e = New(ctx, fmt.Errorf("%v", err), 0).markPanicked()
 	}
 
 	return e
 }
 
+// IsPanic reports whether err, or an error it wraps, was returned by Recover.
+func IsPanic(err error) bool {
+	var e *Error
+	return gerrors.As(err, &e) && e.panicked
+}
+
 // Error wraps an error with additional information, like a stack trace,
 // contextual information, and an http request if provided.
 type Error struct {
@@ -77,6 +90,9 @@ type Error struct {
 	// This is private so that it can be exposed via StackTrace(),
 	// which implements the stackTracker interface.
 	stackTrace errors.StackTrace
+
+	// Whether the error was recovered from a panic.
+	panicked bool
 }
 
 // New returns a new Error instance. If err is already an Error instance,
@@ -98,6 +114,11 @@ func new(err error, skip int) *Error {
 	}
 }
 
+func (e *Error) markPanicked() *Error {
+	e.panicked = true
+	return e
+}
+
 // Error implements the error interface.
 func (e *Error) Error() string {
 	return e.Err.Error()
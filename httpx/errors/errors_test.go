@@ -245,3 +245,57 @@ func TestStackErrors(t *testing.T) {
 		genStacktrace(gerrors.New("no stack"), 100)
 	}, "expected a panic when we are at the limit of the stack frames for skips")
 }
+
+func TestIsPanic(t *testing.T) {
+	ctx := context.Background()
+
+	if !IsPanic(Recover(ctx, "boom")) {
+		t.Error("expected a recovered panic to be reported")
+	}
+	if !IsPanic(fmt.Errorf("wrapped: %w", Recover(ctx, errBoom))) {
+		t.Error("expected a wrapped recovered panic to be reported")
+	}
+	if IsPanic(New(ctx, errBoom, 0)) {
+		t.Error("expected an error that wasn't recovered not to be reported")
+	}
+}
+
+func TestWithRedactedData(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/users?id=1&access_token=this-is-a-secret", nil)
+	req.Header.Set("X-Api-Key", "this-is-a-secret")
+	req.Header.Set("X-Forwarded-For", "jane@example.com")
+	req.Form = url.Values{}
+	req.Form.Add("client_secret", "this-is-a-secret")
+	req.Form.Add("email", "jane@example.com")
+	ctx := WithRequest(context.Background(), req)
+	ctx = WithInfo(ctx, "session_token", "this-is-a-secret")
+	ctx = WithInfo(ctx, "user", "jane@example.com")
+	e := New(ctx, errBoom, 0)
+	r := e.Request()
+
+	if got, want := r.URL.RawQuery, "id=1&access_token=[REDACTED]"; got != want {
+		t.Fatalf("expected request.URL.RawQuery to be %q, got: %q", want, got)
+	}
+
+	if r.Header.Get("X-Api-Key") != "" {
+		t.Fatal("expected request.headers.X-Api-Key to have been removed by the reporter")
+	}
+
+	if got, want := r.Header.Get("X-Forwarded-For"), "[REDACTED]"; got != want {
+		t.Fatalf("expected request.headers.X-Forwarded-For to be %q, got: %q", want, got)
+	}
+
+	if r.Form.Get("client_secret") != "" {
+		t.Fatal("expected request.Form[\"client_secret\"] to have been removed by the reporter")
+	}
+
+	if got, want := r.Form.Get("email"), "[REDACTED]"; got != want {
+		t.Fatalf("expected request.Form[\"email\"] to be %q, got: %q", want, got)
+	}
+
+	for _, key := range []string{"session_token", "user"} {
+		if got, want := e.ContextData()[key], "[REDACTED]"; got != want {
+			t.Fatalf("expected ContextData()[%q] to be %q, got: %v", key, want, got)
+		}
+	}
+}
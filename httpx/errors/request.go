@@ -3,6 +3,8 @@ package errors
 import (
 	"net/http"
 	"net/url"
+
+	"github.com/remind101/pkg/redact"
 )
 
 func safeCloneRequest(req *http.Request) *http.Request {
@@ -31,7 +33,7 @@ func safeCloneRequest(req *http.Request) *http.Request {
 		// Trailer isn't that important for reporting purposes
 		Trailer:    nil,
 		RemoteAddr: req.RemoteAddr,
-		RequestURI: req.RequestURI,
+		RequestURI: redact.Default.Scrub(req.RequestURI),
 	}
 }
 
@@ -46,11 +48,13 @@ func safeCloneURL(u *url.URL) *url.URL {
 		Path:       u.Path,
 		RawPath:    u.RawPath,
 		ForceQuery: u.ForceQuery,
-		RawQuery:   u.RawQuery,
+		RawQuery:   redact.Default.Scrub(u.RawQuery),
 		Fragment:   u.Fragment,
 	}
 }
 
+// sensitiveHeaders are removed even when redact.Default is nil. Other headers
+// with a key that's sensitive to redact.Default are removed too.
 var sensitiveHeaders = map[string]bool{
 	"Authorization": true,
 	"Cookie":        true,
@@ -62,10 +66,10 @@ func safeCloneHeader(header *http.Header) *http.Header {
 	}
 	safeHeader := http.Header{}
 	for key, valueArray := range *header {
-		if _, ok := sensitiveHeaders[key]; ok {
+		if sensitiveHeaders[key] || redact.Default.IsSensitive(key) {
 			continue
 		}
-		safeHeader[key] = copyStringArray(valueArray)
+		safeHeader[key] = scrubStringArray(valueArray)
 	}
 	return &safeHeader
 }
@@ -79,6 +83,16 @@ func copyStringArray(values []string) []string {
 	return safeArray
 }
 
+// scrubStringArray copies values, scrubbed with redact.Default.
+func scrubStringArray(values []string) []string {
+	safeArray := copyStringArray(values)
+	for i, v := range safeArray {
+		safeArray[i] = redact.Default.Scrub(v)
+	}
+	return safeArray
+}
+
+// sensitiveFormKeys are removed like sensitiveHeaders.
 var sensitiveFormKeys = map[string]bool{
 	"password": true,
 }
@@ -89,10 +103,10 @@ func safeCloneForm(form *url.Values) *url.Values {
 	}
 	safeForm := url.Values{}
 	for key, values := range *form {
-		if _, ok := sensitiveFormKeys[key]; ok {
+		if sensitiveFormKeys[key] || redact.Default.IsSensitive(key) {
 			continue
 		}
-		safeForm[key] = copyStringArray(values)
+		safeForm[key] = scrubStringArray(values)
 	}
 	return &safeForm
 }
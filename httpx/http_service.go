@@ -11,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/remind101/pkg/metrics/metricsclient"
 	"github.com/remind101/pkg/retry"
+	"github.com/remind101/pkg/tracing/tracecontext"
 
 	"context"
 )
@@ -53,6 +55,8 @@ func NewClient(c *http.Client) *Client {
 //      1. Request ids will be added to outgoing requests within the
 //         X-Request-Id header.
 //      2. Any 500 errors will be retried.
+//      3. Metrics will be reported for requests, tagged with the service
+//         name, see MetricsTransport.
 //
 // The optional *http.Client parameter can be used to override the default client.
 func NewServiceClient(serviceName string, c *http.Client) *Client {
@@ -67,7 +71,10 @@ func NewServiceClient(serviceName string, c *http.Client) *Client {
 
 	return &Client{
 		Transport: &RequestIDTransport{
-			Transport: NewRetryTransport(retrier, &Transport{Client: c}),
+			Transport: &MetricsTransport{
+				Service:   serviceName,
+				Transport: NewRetryTransport(retrier, &Transport{Client: c}),
+			},
 		},
 	}
 }
@@ -115,7 +122,13 @@ func (t *RetryTransport) RoundTrip(ctx context.Context, req *http.Request) (*htt
 		return t.Transport.RoundTrip(ctx, req)
 	}
 
+	trace := metricsclient.TraceFromContext(req.Context())
+	attempts := 0
 	resp, err := t.Retrier.Retry(func() (interface{}, error) {
+		if attempts++; attempts > 1 && trace != nil {
+			trace.Retried()
+		}
+
 		resp, err := t.Transport.RoundTrip(ctx, req)
 		if err != nil {
 			return nil, err
@@ -137,14 +150,43 @@ func (t *RetryTransport) RoundTrip(ctx context.Context, req *http.Request) (*htt
 	}
 }
 
+// MetricsTransport is a RoundTripper that reports metrics about requests, see
+// metricsclient.Report. Retries are counted when it wraps a RetryTransport.
+type MetricsTransport struct {
+	// Service is the name of the service called, for the service tag.
+	Service   string
+	Transport RoundTripper
+}
+
+func (t *MetricsTransport) RoundTrip(ctx context.Context, req *http.Request) (*http.Response, error) {
+	reqCtx, trace := metricsclient.WithTrace(req.Context())
+	req = req.WithContext(reqCtx)
+
+	start := time.Now()
+	resp, err := t.Transport.RoundTrip(ctx, req)
+	metricsclient.Report(ctx, metricsclient.Request{
+		Service:  t.Service,
+		Method:   req.Method,
+		Response: resp,
+		Err:      err,
+		Duration: time.Since(start),
+		Trace:    trace,
+	})
+	return resp, err
+}
+
 // RequestIDTransport is an http.RoundTripper implementation that adds the
-// embedded request id to outgoing http requests.
+// embedded request id and W3C trace context to outgoing http requests. Headers
+// that were already set on the request are left untouched.
 type RequestIDTransport struct {
 	Transport RoundTripper
 }
 
 func (t *RequestIDTransport) RoundTrip(ctx context.Context, req *http.Request) (*http.Response, error) {
-	req.Header.Add("X-Request-Id", RequestID(ctx))
+	if requestID := RequestID(ctx); requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	tracecontext.Inject(ctx, req.Header)
 	return t.Transport.RoundTrip(ctx, req)
 }
 
@@ -3,11 +3,14 @@ package httpx
 import (
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
 	"context"
+	"github.com/remind101/pkg/metrics/metricsclient"
+	"github.com/remind101/pkg/metrics/metricstest"
 	"github.com/remind101/pkg/retry"
 )
 
@@ -115,6 +118,60 @@ func TestRetryTransport(t *testing.T) {
 
 }
 
+func TestMetricsTransport(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	client := NewServiceClient("users", s.Client())
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	resp, err := client.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.StatusCode, 200; got != want {
+		t.Fatalf("got %d; expected %d", got, want)
+	}
+
+	tags := map[string]string{
+		"service":   "users",
+		"operation": "GET",
+		"status":    "200",
+		"error":     metricsclient.ErrorNone,
+	}
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricRequestTime, tags)
+	metricstest.AssertCounter(t, reporter, metricsclient.MetricRetries, tags, 1)
+}
+
+func TestMetricsTransport_Redirect(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			w.Header().Set("Connection", "close")
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer s.Close()
+
+	client := NewServiceClient("users", s.Client())
+	req, _ := http.NewRequest("GET", s.URL+"/old", nil)
+	if _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	metricstest.AssertNotReported(t, reporter, metricstest.KindCount, metricsclient.MetricRetries, nil)
+}
+
 func TestRetryableRequestNotRetried(t *testing.T) {
 	mockTransport := &MockTransport{responses: make(chan *http.Response)}
 	mockClient := &http.Client{Transport: mockTransport}
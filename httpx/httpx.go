@@ -30,4 +30,6 @@ const (
 	varsKey key = iota
 	requestIDKey
 	routeKey
+	routeRecorderKey
+	routeWrapperKey
 )
@@ -0,0 +1,450 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/timex"
+	"github.com/remind101/pkg/tracing/tracecontext"
+)
+
+// AccessLogFormat is the output format of an access log line.
+type AccessLogFormat int
+
+const (
+	// AccessLogLogfmt writes lines as key=value pairs, quoting values when
+	// needed.
+	AccessLogLogfmt AccessLogFormat = iota
+
+	// AccessLogJSON writes lines as JSON objects.
+	AccessLogJSON
+
+	// AccessLogCombined writes lines in the Apache combined log format. The
+	// configured fields are ignored.
+	AccessLogCombined
+)
+
+// AccessLogField is a field that can be included in access log lines.
+type AccessLogField string
+
+const (
+	FieldMethod    AccessLogField = "method"
+	FieldPath      AccessLogField = "path"
+	FieldRoute     AccessLogField = "route"
+	FieldStatus    AccessLogField = "status"
+	FieldDuration  AccessLogField = "ms"
+	FieldBytesIn   AccessLogField = "bytes_in"
+	FieldBytesOut  AccessLogField = "bytes_out"
+	FieldUserAgent AccessLogField = "user_agent"
+	FieldRemoteIP  AccessLogField = "remote_ip"
+	FieldRealIP    AccessLogField = "real_ip"
+	FieldRequestID AccessLogField = "request_id"
+	FieldTraceID   AccessLogField = "trace_id"
+	FieldError     AccessLogField = "error"
+)
+
+// DefaultAccessLogFields are the fields logged when AccessLogOpts.Fields is
+// empty.
+var DefaultAccessLogFields = []AccessLogField{
+	FieldMethod,
+	FieldPath,
+	FieldStatus,
+	FieldDuration,
+	FieldRequestID,
+	FieldError,
+}
+
+// DefaultAccessLogLevels maps status classes to the level requests are logged
+// at when AccessLogOpts.Levels doesn't include the class.
+var DefaultAccessLogLevels = map[int]logger.Level{
+	1: logger.INFO,
+	2: logger.INFO,
+	3: logger.INFO,
+	4: logger.WARN,
+	5: logger.ERROR,
+}
+
+// AccessLogOpts configures the AccessLogger middleware.
+type AccessLogOpts struct {
+	// Router is used to resolve the route template for FieldRoute when
+	// none was recorded by the Router handling the request, like when the
+	// AccessLogger is inside of it. See httpx.RecordedRoute.
+	Router *httpx.Router
+
+	// Fields to include in each line, in order. The zero value uses
+	// DefaultAccessLogFields.
+	Fields []AccessLogField
+
+	// Format of each line. The zero value is AccessLogLogfmt.
+	Format AccessLogFormat
+
+	// Output is where lines are written. The zero value is os.Stdout.
+	Output io.Writer
+
+	// Levels maps a status class (2 for 2xx, 5 for 5xx...) to the level
+	// requests are logged at. Set a class to logger.OFF to not log it.
+	// Classes that are missing use DefaultAccessLogLevels.
+	Levels map[int]logger.Level
+
+	// SampleRate is the fraction, between 0 and 1, of successful requests
+	// that are logged. Requests that fail, return an error or are slow are
+	// always logged. The zero value logs every request, and SampleNone none
+	// of the successful ones.
+	SampleRate float64
+
+	// SlowThreshold is the duration after which a request is considered
+	// slow, and always logged. The zero value disables it.
+	SlowThreshold time.Duration
+
+	// TrustedProxies are the ip addresses or CIDR ranges, like
+	// "10.0.0.0/8", of the proxies whose X-Forwarded-For and X-Real-Ip
+	// headers are used for FieldRealIP and AccessLogCombined. Without
+	// them, the remote address is logged, since clients can set these
+	// headers to anything.
+	TrustedProxies []string
+}
+
+// SampleNone is the AccessLogOpts.SampleRate that logs none of the successful
+// requests.
+const SampleNone = -1.0
+
+// AccessLogger is middleware that writes an access log line for each request.
+type AccessLogger struct {
+	AccessLogOpts
+
+	// mu serializes writes to Output.
+	mu sync.Mutex
+
+	// trusted are the parsed TrustedProxies.
+	trusted []*net.IPNet
+
+	// handler is the wrapped httpx.Handler
+	handler httpx.Handler
+}
+
+// AccessLog returns an AccessLogger middleware that wraps h. It panics if one
+// of opts.TrustedProxies isn't an ip address or a CIDR range.
+func AccessLog(h httpx.Handler, opts AccessLogOpts) *AccessLogger {
+	if len(opts.Fields) == 0 {
+		opts.Fields = DefaultAccessLogFields
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	return &AccessLogger{
+		AccessLogOpts: opts,
+		trusted:       parseTrustedProxies(opts.TrustedProxies),
+		handler:       h,
+	}
+}
+
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				panic(fmt.Sprintf("middleware: invalid trusted proxy: %q", p))
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			panic(fmt.Sprintf("middleware: invalid trusted proxy: %q", p))
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func (h *AccessLogger) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	rw := NewResponseWriter(w)
+	ctx = httpx.WithRouteRecorder(ctx)
+
+	start := timex.Now()
+	err := h.handler.ServeHTTPContext(ctx, rw, r)
+	duration := timex.Now().Sub(start)
+
+	status := rw.Status()
+	level := h.level(status)
+	if level == logger.OFF || !h.sampled(status, duration, err) {
+		return err
+	}
+
+	e := accessLogEntry{
+		Time:     start,
+		Level:    level,
+		Request:  r,
+		Status:   status,
+		Duration: duration,
+		BytesIn:  body.n,
+		BytesOut: rw.Size(),
+		Err:      err,
+	}
+
+	var line string
+	if h.Format == AccessLogCombined {
+		line = e.combined(h.realIP(r))
+	} else {
+		pairs := make([]interface{}, 0, 2*len(h.Fields))
+		for _, f := range h.Fields {
+			if v, ok := h.value(ctx, f, e); ok {
+				pairs = append(pairs, string(f), v)
+			}
+		}
+		if h.Format == AccessLogJSON {
+			line = e.json(pairs)
+		} else {
+			line = e.logfmt(pairs)
+		}
+	}
+
+	h.mu.Lock()
+	io.WriteString(h.Output, line+"\n")
+	h.mu.Unlock()
+
+	return err
+}
+
+// level returns the level a request with the given status is logged at.
+func (h *AccessLogger) level(status int) logger.Level {
+	class := status / 100
+	if l, ok := h.Levels[class]; ok {
+		return l
+	}
+	if l, ok := DefaultAccessLogLevels[class]; ok {
+		return l
+	}
+	return logger.INFO
+}
+
+// sampled reports whether a request should be logged.
+func (h *AccessLogger) sampled(status int, duration time.Duration, err error) bool {
+	if h.SampleRate == 0 || h.SampleRate >= 1 {
+		return true
+	}
+	if err != nil || status >= 400 {
+		return true
+	}
+	if h.SlowThreshold > 0 && duration >= h.SlowThreshold {
+		return true
+	}
+	return h.SampleRate > 0 && rand.Float64() < h.SampleRate
+}
+
+// value returns the value of a field, and whether it should be logged.
+func (h *AccessLogger) value(ctx context.Context, f AccessLogField, e accessLogEntry) (interface{}, bool) {
+	r := e.Request
+	switch f {
+	case FieldMethod:
+		return r.Method, true
+	case FieldPath:
+		return r.URL.Path, true
+	case FieldRoute:
+		if route := httpx.RecordedRoute(ctx); route != nil {
+			if tpl := route.GetPathTemplate(); tpl != "" {
+				return tpl, true
+			}
+			return "unknown", true
+		}
+		if h.Router == nil {
+			return nil, false
+		}
+		return otTemplatePath(h.Router, r), true
+	case FieldStatus:
+		return e.Status, true
+	case FieldDuration:
+		return int(e.Duration / time.Millisecond), true
+	case FieldBytesIn:
+		return e.BytesIn, true
+	case FieldBytesOut:
+		return e.BytesOut, true
+	case FieldUserAgent:
+		return r.UserAgent(), true
+	case FieldRemoteIP:
+		return remoteIP(r), true
+	case FieldRealIP:
+		return h.realIP(r), true
+	case FieldRequestID:
+		return httpx.RequestID(ctx), true
+	case FieldTraceID:
+		sc := tracecontext.Current(ctx)
+		if !sc.TraceID.IsValid() {
+			sc = tracecontext.Extract(r.Header)
+		}
+		if !sc.TraceID.IsValid() {
+			return nil, false
+		}
+		return sc.TraceID.String(), true
+	case FieldError:
+		if e.Err == nil {
+			return nil, false
+		}
+		return e.Err.Error(), true
+	}
+	return nil, false
+}
+
+// remoteIP returns the ip address of the peer that sent the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// realIP returns the ip address of the client, as reported by trusted proxies
+// in the X-Forwarded-For or X-Real-Ip headers, falling back to the remote
+// address. X-Forwarded-For is read from the right, skipping the trusted
+// proxies, since the addresses on the left can be set by the client.
+func (h *AccessLogger) realIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if !h.isTrusted(ip) {
+		return ip
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !h.isTrusted(hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if real := r.Header.Get("X-Real-Ip"); real != "" {
+		return real
+	}
+	return ip
+}
+
+// isTrusted reports whether ip is one of the TrustedProxies.
+func (h *AccessLogger) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range h.trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// accessLogEntry holds the information about a request that's logged.
+type accessLogEntry struct {
+	Time     time.Time
+	Level    logger.Level
+	Request  *http.Request
+	Status   int
+	Duration time.Duration
+	BytesIn  int64
+	BytesOut int
+	Err      error
+}
+
+func (e accessLogEntry) logfmt(pairs []interface{}) string {
+	var b strings.Builder
+	b.WriteString("time=" + e.Time.Format(time.RFC3339))
+	b.WriteString(" level=" + logger.FormatLevel(e.Level))
+	b.WriteString(" msg=request")
+	for i := 0; i < len(pairs); i += 2 {
+		b.WriteString(" " + pairs[i].(string) + "=" + logfmtValue(pairs[i+1]))
+	}
+	return b.String()
+}
+
+func (e accessLogEntry) json(pairs []interface{}) string {
+	m := make(map[string]interface{}, len(pairs)/2+3)
+	m["time"] = e.Time.Format(time.RFC3339)
+	m["level"] = logger.FormatLevel(e.Level)
+	m["msg"] = "request"
+	for i := 0; i < len(pairs); i += 2 {
+		m[pairs[i].(string)] = pairs[i+1]
+	}
+	raw, _ := json.Marshal(m)
+	return string(raw)
+}
+
+// combined formats the entry in the Apache combined log format:
+//
+//	%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+func (e accessLogEntry) combined(ip string) string {
+	r := e.Request
+
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	size := "-"
+	if e.BytesOut > 0 {
+		size = strconv.Itoa(e.BytesOut)
+	}
+
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s %q %q`,
+		ip,
+		user,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method, r.RequestURI, r.Proto,
+		e.Status,
+		size,
+		r.Referer(),
+		r.UserAgent(),
+	)
+}
+
+// logfmtValue formats v, quoting it if it contains spaces, quotes, equal
+// signs or control characters.
+func logfmtValue(v interface{}) string {
+	s := fmt.Sprintf("%v", v)
+	if s == "" {
+		return `""`
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
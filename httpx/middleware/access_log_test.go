@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/timex"
+)
+
+func stubNow(t *testing.T) {
+	now := time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC)
+	timex.Now = func() time.Time { return now }
+	t.Cleanup(func() { timex.Now = time.Now })
+}
+
+func TestAccessLog(t *testing.T) {
+	stubNow(t)
+
+	tests := []struct {
+		opts    AccessLogOpts
+		status  int
+		err     error
+		headers map[string]string
+		want    string
+	}{
+		{
+			AccessLogOpts{},
+			200, nil, nil,
+			`time=2017-03-04T05:06:07Z level=info msg=request method=POST path=/users/1 status=200 ms=0 request_id=abc`,
+		},
+		{
+			AccessLogOpts{},
+			500, errors.New("boom happened"), nil,
+			`time=2017-03-04T05:06:07Z level=error msg=request method=POST path=/users/1 status=500 ms=0 request_id=abc error="boom happened"`,
+		},
+		{
+			AccessLogOpts{Fields: []AccessLogField{FieldStatus, FieldBytesIn, FieldBytesOut, FieldUserAgent, FieldRemoteIP, FieldRealIP}},
+			404, nil, map[string]string{"User-Agent": "curl/7.1 (x)", "X-Forwarded-For": "10.0.0.1, 10.0.0.2"},
+			`time=2017-03-04T05:06:07Z level=warn msg=request status=404 bytes_in=5 bytes_out=2 user_agent="curl/7.1 (x)" remote_ip=192.0.2.1 real_ip=192.0.2.1`,
+		},
+		{
+			AccessLogOpts{Fields: []AccessLogField{FieldRealIP}, TrustedProxies: []string{"192.0.2.0/24"}},
+			200, nil, map[string]string{"X-Forwarded-For": "10.0.0.1, 10.0.0.2"},
+			`time=2017-03-04T05:06:07Z level=info msg=request real_ip=10.0.0.2`,
+		},
+		{
+			AccessLogOpts{Fields: []AccessLogField{FieldRealIP}, TrustedProxies: []string{"192.0.2.1", "10.0.0.2"}},
+			200, nil, map[string]string{"X-Forwarded-For": "10.0.0.1, 10.0.0.2"},
+			`time=2017-03-04T05:06:07Z level=info msg=request real_ip=10.0.0.1`,
+		},
+		{
+			AccessLogOpts{Fields: []AccessLogField{FieldRealIP}, TrustedProxies: []string{"192.0.2.1"}},
+			200, nil, map[string]string{"X-Real-Ip": "10.0.0.3"},
+			`time=2017-03-04T05:06:07Z level=info msg=request real_ip=10.0.0.3`,
+		},
+		{
+			AccessLogOpts{Fields: []AccessLogField{FieldTraceID}},
+			200, nil, map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			`time=2017-03-04T05:06:07Z level=info msg=request trace_id=4bf92f3577b34da6a3ce929d0e0e4736`,
+		},
+		{
+			AccessLogOpts{Levels: map[int]logger.Level{2: logger.OFF}},
+			200, nil, nil,
+			``,
+		},
+		{
+			AccessLogOpts{Format: AccessLogCombined},
+			200, nil, map[string]string{"User-Agent": "curl", "Referer": "http://example.com"},
+			`192.0.2.1 - - [04/Mar/2017:05:06:07 +0000] "POST /users/1 HTTP/1.1" 200 2 "http://example.com" "curl"`,
+		},
+	}
+
+	for i, tt := range tests {
+		b := new(bytes.Buffer)
+		tt.opts.Output = b
+
+		status, reqErr := tt.status, tt.err
+		h := AccessLog(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(status)
+			w.Write([]byte("ok"))
+			return reqErr
+		}), tt.opts)
+
+		ctx := httpx.WithRequestID(context.Background(), "abc")
+		req := httptest.NewRequest("POST", "/users/1", strings.NewReader("hello"))
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+
+		if err := h.ServeHTTPContext(ctx, httptest.NewRecorder(), req); err != tt.err {
+			t.Fatalf("#%d: err %v", i, err)
+		}
+
+		if got, want := strings.TrimSuffix(b.String(), "\n"), tt.want; got != want {
+			t.Errorf("#%d: got %s; expected %s", i, got, want)
+		}
+	}
+}
+
+func TestAccessLog_RecordedRoute(t *testing.T) {
+	stubNow(t)
+
+	b := new(bytes.Buffer)
+	r := httpx.NewRouter()
+	r.HandleFunc("/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}).Methods("GET")
+	h := AccessLog(r, AccessLogOpts{
+		Fields: []AccessLogField{FieldRoute, FieldStatus},
+		Output: b,
+	})
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	if err := h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := b.String(), "time=2017-03-04T05:06:07Z level=info msg=request route=/users/{id} status=200\n"; got != want {
+		t.Errorf("got %q; expected %q", got, want)
+	}
+}
+
+func TestAccessLog_JSON(t *testing.T) {
+	stubNow(t)
+
+	b := new(bytes.Buffer)
+	r := httpx.NewRouter()
+	h := AccessLog(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(201)
+		return nil
+	}), AccessLogOpts{
+		Router: r,
+		Fields: []AccessLogField{FieldMethod, FieldRoute, FieldStatus},
+		Format: AccessLogJSON,
+		Output: b,
+	})
+	r.Handle("/users/{id}", h).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/users/1", nil)
+	if err := r.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"time":   "2017-03-04T05:06:07Z",
+		"level":  "info",
+		"msg":    "request",
+		"method": "POST",
+		"route":  "/users/{id}",
+		"status": float64(201),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %v; expected %v", k, got[k], v)
+		}
+	}
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	var status int
+	h := AccessLog(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(status)
+		return nil
+	}), AccessLogOpts{
+		Fields:     []AccessLogField{FieldStatus},
+		Output:     b,
+		SampleRate: 0.0001,
+	})
+
+	serve := func() {
+		req := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req)
+	}
+
+	status = 200
+	for i := 0; i < 100; i++ {
+		serve()
+	}
+	if got := strings.Count(b.String(), "status=200"); got > 5 {
+		t.Errorf("got %d successful requests logged; expected them to be sampled", got)
+	}
+
+	status = 503
+	for i := 0; i < 10; i++ {
+		serve()
+	}
+	if got, want := strings.Count(b.String(), "status=503"), 10; got != want {
+		t.Errorf("got %d failed requests logged; expected %d", got, want)
+	}
+}
+
+func TestAccessLog_SampleNone(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	var status int
+	h := AccessLog(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(status)
+		return nil
+	}), AccessLogOpts{
+		Fields:     []AccessLogField{FieldStatus},
+		Output:     b,
+		SampleRate: SampleNone,
+	})
+
+	for _, status = range []int{200, 200, 500} {
+		h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	if got, want := strings.TrimSuffix(b.String(), "\n"), "status=500"; !strings.HasSuffix(got, want) || strings.Contains(got, "status=200") {
+		t.Errorf("got %s; expected only the failed request to be logged", got)
+	}
+}
@@ -39,12 +39,15 @@ func LogTo(h httpx.Handler, g loggerGenerator) httpx.Handler {
 }
 
 // InsertLogger returns an httpx.Handler middleware that will call f to generate
-// a logger, then insert it into the context.
+// a logger, then insert it into the context. Messages logged with the context
+// are correlated with the span started by the tracing middleware it wraps, see
+// logger.WithSpanRecorder.
 func InsertLogger(h httpx.Handler, g loggerGenerator) httpx.Handler {
 	return httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 		l := g(ctx, r)
 
 		ctx = logger.WithLogger(ctx, l)
+		ctx = logger.WithSpanRecorder(ctx)
 		r = r.WithContext(ctx)
 
 		return h.ServeHTTPContext(ctx, w, r)
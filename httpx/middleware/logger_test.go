@@ -10,6 +10,7 @@ import (
 
 	"github.com/remind101/pkg/httpx"
 	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/tracing/oteltest"
 	"context"
 )
 
@@ -38,6 +39,40 @@ func TestLogger(t *testing.T) {
 	}
 }
 
+func TestLogger_TraceCorrelation(t *testing.T) {
+	exporter, restore := oteltest.Install()
+	defer restore()
+
+	b := new(bytes.Buffer)
+	r := httpx.NewRouter()
+	r.HandleFunc("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		logger.Info(ctx, "handled")
+		return nil
+	})
+	h := LogTo(OtelTracing(r, r), stdLogger(logger.DEBUG, b))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	if err := h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	ids := "trace_id=" + spans[0].SpanContext.TraceID().String() + " span_id=" + spans[0].SpanContext.SpanID().String()
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", b.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, ids) {
+			t.Errorf("%s; want %s", line, ids)
+		}
+	}
+}
+
 // set to warn, check it logs nothing
 func TestLoggerUnderLevel(t *testing.T) {
 	b := new(bytes.Buffer)
@@ -9,6 +9,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
 )
 
 type OpentracingTracer struct {
@@ -45,6 +46,7 @@ func (h *OpentracingTracer) ServeHTTPContext(ctx context.Context, w http.Respons
 	defer span.Finish()
 	ctx = opentracing.ContextWithSpan(ctx, span)
 	r = r.WithContext(ctx)
+	logger.RecordSpan(ctx)
 
 	rw := NewResponseWriter(w)
 	reqErr := h.handler.ServeHTTPContext(ctx, rw, r)
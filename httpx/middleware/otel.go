@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	dd_ext "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// otelTracerName is the instrumentation name used for spans created by this
+// package.
+const otelTracerName = "github.com/remind101/pkg/httpx/middleware"
+
+// OtelTracer is the OpenTelemetry counterpart of OpentracingTracer. It uses the
+// global TracerProvider and TextMapPropagator, and tags spans the same way.
+type OtelTracer struct {
+	handler httpx.Handler
+	router  *httpx.Router
+}
+
+func OtelTracing(h httpx.Handler, router *httpx.Router) *OtelTracer {
+	return &OtelTracer{h, router}
+}
+
+func (h *OtelTracer) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	path := otTemplatePath(h.router, r)
+	route := fmt.Sprintf("%s %s", r.Method, path)
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer(otelTracerName).Start(ctx, "server.request", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String(dd_ext.ResourceName, route),
+		attribute.String(dd_ext.SpanType, "web"),
+		attribute.String(dd_ext.HTTPMethod, r.Method),
+		attribute.String(dd_ext.HTTPURL, r.RequestURI),
+	)
+
+	if rid := httpx.RequestID(ctx); rid != "" {
+		span.SetAttributes(attribute.String("request_id", rid))
+	}
+
+	r = r.WithContext(ctx)
+	logger.RecordSpan(ctx)
+
+	rw := NewResponseWriter(w)
+	reqErr := h.handler.ServeHTTPContext(ctx, rw, r)
+	if reqErr != nil {
+		span.RecordError(reqErr)
+		span.SetStatus(codes.Error, reqErr.Error())
+		if _, ok := reqErr.(fmt.Formatter); ok {
+			span.SetAttributes(attribute.String(dd_ext.ErrorStack, fmt.Sprintf("%+v", reqErr)))
+		}
+	}
+	span.SetAttributes(attribute.Int(dd_ext.HTTPCode, rw.Status()))
+
+	return reqErr
+}
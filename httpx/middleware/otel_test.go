@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/tracing/oteltest"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestOtelTracing(t *testing.T) {
+	exporter, restore := oteltest.Install()
+	defer restore()
+
+	r := httpx.NewRouter()
+	r.Handle("/users/{user_id}", httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusNotFound)
+		return errors.New("not found")
+	})).Methods("DELETE")
+
+	m := OtelTracing(r, r)
+
+	ctx := httpx.WithRequestID(context.Background(), "abc")
+	resp := httptest.NewRecorder()
+	req := newRequest("DELETE", "/users/23")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	if err := m.ServeHTTPContext(ctx, resp, req); err == nil {
+		t.Fatal("expected error to be returned")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if got, want := span.Parent.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("parent trace id => %s; want %s", got, want)
+	}
+	if got, want := oteltest.Attribute(span, "resource.name").AsString(), "DELETE /users/{user_id}"; got != want {
+		t.Errorf("resource.name => %s; want %s", got, want)
+	}
+	if got, want := oteltest.Attribute(span, "request_id").AsString(), "abc"; got != want {
+		t.Errorf("request_id => %s; want %s", got, want)
+	}
+	if got, want := oteltest.Attribute(span, "http.status_code").AsInt64(), int64(404); got != want {
+		t.Errorf("http.status_code => %d; want %d", got, want)
+	}
+	if got, want := span.Status.Code, codes.Error; got != want {
+		t.Errorf("status => %v; want %v", got, want)
+	}
+}
@@ -12,6 +12,10 @@ import (
 // id from an http.Request.
 var DefaultRequestIDExtractor = HeaderExtractor([]string{"X-Request-Id", "Request-Id"})
 
+// DefaultRequestIDGenerator is the generator used by ExtractRequestID to
+// create request ids for requests that arrive without one.
+var DefaultRequestIDGenerator httpx.RequestIDGenerator = httpx.NewUUIDv4
+
 // RequestID is middleware that extracts a request id from the headers and
 // inserts it into the context.
 type RequestID struct {
@@ -20,13 +24,32 @@ type RequestID struct {
 	// id from the `X-Request-ID` or `Request-ID` headers.
 	Extractor func(*http.Request) string
 
+	// Sanitizer, if set, is applied to extracted request ids. It can
+	// clean up the id, or return an empty string to reject it, in which
+	// case a new id is generated.
+	Sanitizer func(string) string
+
+	// Generator, if set, is used to generate a request id when the request
+	// did not include a usable one.
+	Generator httpx.RequestIDGenerator
+
+	// ResponseHeader, if set, is the response header the request id is
+	// echoed back in.
+	ResponseHeader string
+
 	// handler is the wrapped httpx.Handler.
 	handler httpx.Handler
 }
 
+// ExtractRequestID returns a RequestID middleware that sanitizes inbound
+// request ids, generates one with DefaultRequestIDGenerator if none was
+// provided, and echoes it back in the X-Request-Id response header.
 func ExtractRequestID(h httpx.Handler) *RequestID {
 	return &RequestID{
-		handler: h,
+		Sanitizer:      httpx.SanitizeRequestID,
+		Generator:      DefaultRequestIDGenerator,
+		ResponseHeader: httpx.RequestIDHeader,
+		handler:        h,
 	}
 }
 
@@ -39,6 +62,18 @@ func (h *RequestID) ServeHTTPContext(ctx context.Context, w http.ResponseWriter,
 	}
 	requestID := e(r)
 
+	if requestID != "" && h.Sanitizer != nil {
+		requestID = h.Sanitizer(requestID)
+	}
+
+	if requestID == "" && h.Generator != nil {
+		requestID = h.Generator()
+	}
+
+	if requestID != "" && h.ResponseHeader != "" {
+		w.Header().Set(h.ResponseHeader, requestID)
+	}
+
 	ctx = httpx.WithRequestID(ctx, requestID)
 	r = r.WithContext(ctx)
 
@@ -48,3 +48,38 @@ func TestRequestID(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractRequestID(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		id     string
+	}{
+		{http.Header{http.CanonicalHeaderKey("X-Request-ID"): []string{"1234"}}, "1234"},
+		{http.Header{http.CanonicalHeaderKey("X-Request-ID"): []string{"12 34\n"}}, "1234"},
+		{http.Header{http.CanonicalHeaderKey("X-Request-ID"): []string{"\n"}}, "generated"},
+		{http.Header{http.CanonicalHeaderKey("Foo"): []string{"1234"}}, "generated"},
+	}
+
+	for _, tt := range tests {
+		m := ExtractRequestID(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if got, want := httpx.RequestID(ctx), tt.id; got != want {
+				t.Fatalf("RequestID => %s; want %s", got, want)
+			}
+			return nil
+		}))
+		m.Generator = func() string { return "generated" }
+
+		ctx := context.Background()
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+
+		if err := m.ServeHTTPContext(ctx, resp, req); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := resp.Header().Get("X-Request-Id"), tt.id; got != want {
+			t.Fatalf("X-Request-Id response header => %s; want %s", got, want)
+		}
+	}
+}
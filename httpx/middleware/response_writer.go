@@ -1,55 +1,176 @@
 package middleware
 
 import (
-	"bufio"
-	"fmt"
-	"net"
+	"io"
 	"net/http"
+	"time"
 )
 
 // ResponseWriter is a wrapper around http.ResponseWriter that provides extra information about
 // the response.
+//
+// The value returned by NewResponseWriter also implements http.Hijacker,
+// io.ReaderFrom and http.Pusher when, and only when, the wrapped
+// http.ResponseWriter does, so wrapping doesn't take capabilities away from
+// handlers further down the chain.
 type ResponseWriter interface {
 	http.ResponseWriter
 	http.Flusher
 	// Status returns the status code of the response or 200 if the response has not been written.
 	Status() int
+	// Size returns the number of bytes written to the response body.
+	Size() int
+	// Written returns whether the headers have been written.
+	Written() bool
+	// TimeToFirstByte returns the time between the creation of the
+	// ResponseWriter and the headers being written, or 0 if they haven't been.
+	TimeToFirstByte() time.Duration
+	// Unwrap returns the wrapped http.ResponseWriter, so it can be used with
+	// http.ResponseController.
+	Unwrap() http.ResponseWriter
 }
 
 // NewResponseWriter creates a ResponseWriter that wraps an http.ResponseWriter
-func NewResponseWriter(rw http.ResponseWriter) ResponseWriter {
-	return &responseWriter{rw, http.StatusOK}
+func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, start: time.Now()}
+
+	h, isHijacker := w.(http.Hijacker)
+	_, isReaderFrom := w.(io.ReaderFrom)
+	p, isPusher := w.(http.Pusher)
+	rf := readerFrom{rw}
+
+	switch {
+	case isHijacker && isReaderFrom && isPusher:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{rw, h, rf, p}
+	case isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, h, rf}
+	case isHijacker && isPusher:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, h, p}
+	case isReaderFrom && isPusher:
+		return struct {
+			*responseWriter
+			io.ReaderFrom
+			http.Pusher
+		}{rw, rf, p}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, h}
+	case isReaderFrom:
+		return struct {
+			*responseWriter
+			io.ReaderFrom
+		}{rw, rf}
+	case isPusher:
+		return struct {
+			*responseWriter
+			http.Pusher
+		}{rw, p}
+	}
+	return rw
 }
 
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status    int
+	size      int
+	written   bool
+	start     time.Time
+	firstByte time.Duration
 }
 
 func (rw *responseWriter) WriteHeader(s int) {
-	rw.status = s
+	// Informational responses, other than 101 Switching Protocols, can be
+	// followed by the actual response headers.
+	if !rw.written && (s >= 200 || s == http.StatusSwitchingProtocols) {
+		rw.status = s
+		rw.markWritten()
+	}
 	rw.ResponseWriter.WriteHeader(s)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.markWritten()
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+func (rw *responseWriter) markWritten() {
+	if !rw.written {
+		rw.written = true
+		rw.firstByte = time.Since(rw.start)
+	}
+}
+
 func (rw *responseWriter) Status() int {
 	return rw.status
 }
 
-func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
-	if !ok {
-		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
-	}
-	return hijacker.Hijack()
+func (rw *responseWriter) Size() int {
+	return rw.size
+}
+
+func (rw *responseWriter) Written() bool {
+	return rw.written
 }
 
+func (rw *responseWriter) TimeToFirstByte() time.Duration {
+	return rw.firstByte
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// CloseNotify implements the deprecated http.CloseNotifier interface. If the
+// wrapped http.ResponseWriter doesn't support it, the returned channel never
+// receives a value.
 func (rw *responseWriter) CloseNotify() <-chan bool {
-	return rw.ResponseWriter.(http.CloseNotifier).CloseNotify()
+	if cn, ok := rw.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
 }
 
 func (rw *responseWriter) Flush() {
-	flusher, ok := rw.ResponseWriter.(http.Flusher)
-	if ok {
-		flusher.Flush()
+	rw.FlushError()
+}
+
+// FlushError flushes the wrapped http.ResponseWriter, and returns
+// http.ErrNotSupported when it can't be flushed, so that
+// http.ResponseController.Flush reports it.
+func (rw *responseWriter) FlushError() error {
+	err := http.NewResponseController(rw.ResponseWriter).Flush()
+	if err == nil {
+		rw.markWritten()
 	}
+	return err
+}
+
+// readerFrom implements io.ReaderFrom for a responseWriter whose wrapped
+// http.ResponseWriter implements it, keeping track of the bytes written.
+type readerFrom struct {
+	rw *responseWriter
+}
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.rw.markWritten()
+	n, err := r.rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.rw.size += int(n)
+	return n, err
 }
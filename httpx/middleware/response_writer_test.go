@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	resp := httptest.NewRecorder()
+	rw := NewResponseWriter(resp)
+
+	if rw.Written() {
+		t.Fatal("expected headers to not be written")
+	}
+	if got, want := rw.Status(), 200; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	rw.WriteHeader(http.StatusInternalServerError)
+	rw.Write([]byte("hello"))
+
+	if got, want := rw.Status(), http.StatusCreated; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if got, want := rw.Size(), 5; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if !rw.Written() {
+		t.Error("expected headers to be written")
+	}
+	if rw.TimeToFirstByte() <= 0 {
+		t.Error("expected time to first byte to be recorded")
+	}
+	if got := rw.Unwrap(); got != resp {
+		t.Errorf("got %v; expected the wrapped ResponseWriter", got)
+	}
+
+	// httptest.ResponseRecorder doesn't support these.
+	if _, ok := rw.(http.Hijacker); ok {
+		t.Error("expected ResponseWriter to not implement http.Hijacker")
+	}
+	if _, ok := rw.(io.ReaderFrom); ok {
+		t.Error("expected ResponseWriter to not implement io.ReaderFrom")
+	}
+
+	select {
+	case <-rw.(http.CloseNotifier).CloseNotify():
+		t.Error("expected CloseNotify channel to not fire")
+	default:
+	}
+}
+
+func TestResponseWriter_Interfaces(t *testing.T) {
+	var (
+		rw      ResponseWriter
+		flushed error
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw = NewResponseWriter(NewResponseWriter(w))
+		if _, ok := rw.(http.Hijacker); !ok {
+			t.Error("expected ResponseWriter to implement http.Hijacker")
+		}
+		if _, ok := rw.(http.Pusher); ok {
+			t.Error("expected ResponseWriter to not implement http.Pusher")
+		}
+		rf, ok := rw.(io.ReaderFrom)
+		if !ok {
+			t.Fatal("expected ResponseWriter to implement io.ReaderFrom")
+		}
+		rf.ReadFrom(strings.NewReader("hello world"))
+		flushed = http.NewResponseController(rw).Flush()
+	}))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if flushed != nil {
+		t.Errorf("expected ResponseController to flush, got %v", flushed)
+	}
+	if got, want := rw.Size(), 11; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+}
+
+func TestResponseWriter_FlushNotSupported(t *testing.T) {
+	rw := NewResponseWriter(struct{ http.ResponseWriter }{httptest.NewRecorder()})
+
+	if err := http.NewResponseController(rw).Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("got %v; expected %v", err, http.ErrNotSupported)
+	}
+	if rw.Written() {
+		t.Error("expected the response not to be written")
+	}
+}
@@ -152,3 +152,99 @@ func (tw *timeoutWriter) writeHeader(code int) {
 func (tw *timeoutWriter) isModified() bool {
 	return tw.modified || len(tw.Header()) > 0
 }
+
+// StreamingTimeoutHandler returns a Handler that runs h with the given time
+// limit, without buffering the response.
+//
+// The deadline is enforced through the request context, which h is expected
+// to honor, and through the write deadline of the underlying connection, so
+// writes made after the deadline fail. If h returns after the deadline
+// without having written a response, the handler returns an error that
+// satisfies the timeoutError interface, like TimeoutHandler does.
+//
+// Unlike TimeoutHandler, h runs in the calling goroutine and the
+// ResponseWriter keeps supporting the Flusher and Hijacker interfaces, which
+// makes it suitable for streaming responses.
+func StreamingTimeoutHandler(h httpx.Handler, dt time.Duration) httpx.Handler {
+	return &streamingTimeoutHandler{
+		handler: h,
+		dt:      dt,
+	}
+}
+
+type streamingTimeoutHandler struct {
+	handler httpx.Handler
+	dt      time.Duration
+}
+
+func (h *streamingTimeoutHandler) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, cancelCtx := context.WithTimeout(ctx, h.dt)
+	defer cancelCtx()
+
+	// Not every ResponseWriter supports write deadlines, in which case we
+	// rely on the context alone.
+	deadline, _ := ctx.Deadline()
+	http.NewResponseController(w).SetWriteDeadline(deadline)
+
+	r = r.WithContext(ctx)
+	rw := NewResponseWriter(w)
+
+	err := h.handler.ServeHTTPContext(ctx, rw, r)
+	if ctx.Err() == context.DeadlineExceeded && !rw.Written() {
+		return errors.New(ctx, ErrHandlerTimeout, 0)
+	}
+	return err
+}
+
+// RouteTimeout is middleware that runs each request with the time limit set on
+// its route with httpx.Route.Timeout, or Default when the route doesn't set one.
+type RouteTimeout struct {
+	// Default is the time limit for routes that don't set one. Zero means
+	// no time limit.
+	Default time.Duration
+
+	// Streaming enforces time limits with StreamingTimeoutHandler instead of
+	// TimeoutHandler.
+	Streaming bool
+
+	router  *httpx.Router
+	handler httpx.Handler
+}
+
+// RouteTimeouts returns a RouteTimeout middleware that reads the time limit of
+// each request from the Route matched by router. When h is router, the time
+// limit is applied by router once it matched the request, otherwise the
+// request is matched twice.
+func RouteTimeouts(h httpx.Handler, router *httpx.Router, dt time.Duration) *RouteTimeout {
+	return &RouteTimeout{
+		Default: dt,
+		router:  router,
+		handler: h,
+	}
+}
+
+func (h *RouteTimeout) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if h.handler == httpx.Handler(h.router) {
+		return h.handler.ServeHTTPContext(httpx.WithRouteWrapper(ctx, h.wrap), w, r)
+	}
+	route, _, _ := h.router.Handler(r)
+	return h.wrap(route, h.handler).ServeHTTPContext(ctx, w, r)
+}
+
+// wrap returns handler with the time limit of route.
+func (h *RouteTimeout) wrap(route *httpx.Route, handler httpx.Handler) httpx.Handler {
+	dt := h.Default
+	if route != nil {
+		if t, ok := route.GetTimeout(); ok {
+			dt = t
+		}
+	}
+
+	if dt <= 0 {
+		return handler
+	}
+	if h.Streaming {
+		return StreamingTimeoutHandler(handler, dt)
+	}
+	return TimeoutHandler(handler, dt)
+}
@@ -157,3 +157,79 @@ func runTimeoutTest(tt timeoutTest, t *testing.T) {
 		}
 	}
 }
+
+func TestStreamingTimeoutHandler(t *testing.T) {
+	h := StreamingTimeoutHandler(httpx.HandlerFunc(func(ctx context.Context, rw http.ResponseWriter, r *http.Request) error {
+		if _, ok := rw.(http.Flusher); !ok {
+			t.Error("expected ResponseWriter to implement http.Flusher")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}), 10*time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	err := h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req)
+	compareError(t, err, ErrHandlerTimeout)
+}
+
+func TestStreamingTimeoutHandler_Written(t *testing.T) {
+	h := StreamingTimeoutHandler(httpx.HandlerFunc(func(ctx context.Context, rw http.ResponseWriter, r *http.Request) error {
+		fmt.Fprintln(rw, "data: 1")
+		rw.(http.Flusher).Flush()
+		<-ctx.Done()
+		return nil
+	}), 10*time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp := httptest.NewRecorder()
+	if err := h.ServeHTTPContext(context.Background(), resp, req); err != nil {
+		t.Fatalf("expected no error once the response is written, got %v", err)
+	}
+
+	if !resp.Flushed {
+		t.Error("expected response to be flushed")
+	}
+	if got, want := resp.Body.String(), "data: 1\n"; got != want {
+		t.Errorf("got: %#v; expected %#v", got, want)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	sleep := func(ctx context.Context, rw http.ResponseWriter, r *http.Request) error {
+		select {
+		case <-ctx.Done():
+		case <-time.After(30 * time.Millisecond):
+			rw.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+
+	router := httpx.NewRouter()
+	router.HandleFunc("/default", sleep)
+	router.HandleFunc("/slow", sleep).Timeout(time.Second)
+	router.HandleFunc("/none", sleep).Timeout(0)
+
+	// The Router applies the time limits when it's the handler, otherwise
+	// RouteTimeout matches the request itself.
+	handlers := []httpx.Handler{router, httpx.HandlerFunc(router.ServeHTTPContext)}
+
+	for i := 0; i < 4; i++ {
+		h := RouteTimeouts(handlers[i%2], router, 10*time.Millisecond)
+		h.Streaming = i >= 2
+
+		tests := []struct {
+			path string
+			err  error
+		}{
+			{"/default", ErrHandlerTimeout},
+			{"/slow", nil},
+			{"/none", nil},
+		}
+
+		for _, tt := range tests {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			err := h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req)
+			compareError(t, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/tracing/tracecontext"
+)
+
+// TraceContext is middleware that extracts the W3C traceparent, tracestate and
+// baggage headers and inserts them into the context, so that they can be
+// propagated to downstream services. Baggage items are added as fields to the
+// logger embedded within the context.
+type TraceContext struct {
+	// BaggageHeaders maps baggage keys to header names. Matching baggage
+	// items are inserted into the context with httpx.WithHeader, so they
+	// can be forwarded as regular headers, like ExtractHeader does.
+	BaggageHeaders map[string]string
+
+	// handler is the wrapped httpx.Handler.
+	handler httpx.Handler
+}
+
+func ExtractTraceContext(h httpx.Handler) *TraceContext {
+	return &TraceContext{
+		handler: h,
+	}
+}
+
+// ServeHTTPContext implements the httpx.Handler interface.
+func (h *TraceContext) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	sc := tracecontext.Extract(r.Header)
+	ctx = tracecontext.WithSpanContext(ctx, sc)
+
+	if len(sc.Baggage) > 0 {
+		if l, ok := logger.FromContext(ctx); ok {
+			pairs := make([]interface{}, 0, 2*len(sc.Baggage))
+			for _, m := range sc.Baggage {
+				pairs = append(pairs, "baggage."+m.Key, m.Value)
+			}
+			ctx = logger.WithLogger(ctx, l.With(pairs...))
+		}
+
+		for key, header := range h.BaggageHeaders {
+			if v, ok := sc.Baggage.Get(key); ok {
+				ctx = httpx.WithHeader(ctx, header, v)
+			}
+		}
+	}
+
+	r = r.WithContext(ctx)
+
+	return h.handler.ServeHTTPContext(ctx, w, r)
+}
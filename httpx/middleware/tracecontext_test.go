@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/tracing/tracecontext"
+)
+
+func TestTraceContext(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	m := ExtractTraceContext(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		sc, ok := tracecontext.FromContext(ctx)
+		if !ok {
+			t.Fatal("expected span context in context")
+		}
+		if got, want := sc.TraceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+			t.Errorf("TraceID => %s; want %s", got, want)
+		}
+		if got, want := httpx.Header(ctx, "X-Tier"), "gold"; got != want {
+			t.Errorf("X-Tier => %s; want %s", got, want)
+		}
+		logger.Info(ctx, "hello")
+		return nil
+	}))
+	m.BaggageHeaders = map[string]string{"tier": "X-Tier"}
+
+	ctx := logger.WithLogger(context.Background(), logger.New(log.New(b, "", 0), logger.INFO))
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("baggage", "tier=gold")
+
+	if err := m.ServeHTTPContext(ctx, resp, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := b.String(), "baggage.tier=gold"; !strings.Contains(got, want) {
+		t.Errorf("log => %q; want %q", got, want)
+	}
+}
@@ -1,6 +1,22 @@
 package httpx
 
-import "context"
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// RequestIDHeader is the header used to propagate request ids between
+// services.
+const RequestIDHeader = "X-Request-Id"
+
+// MaxRequestIDLength is the maximum length of a request id accepted by
+// SanitizeRequestID. Longer ids are truncated.
+var MaxRequestIDLength = 128
 
 // WithRequestID inserts a RequestID into the context.
 func WithRequestID(ctx context.Context, requestID string) context.Context {
@@ -12,3 +28,76 @@ func RequestID(ctx context.Context) string {
 	requestID, _ := ctx.Value(requestIDKey).(string)
 	return requestID
 }
+
+// RequestIDGenerator is a function that generates a new, unique request id.
+type RequestIDGenerator func() string
+
+// NewUUIDv4 generates a random (version 4) UUID request id.
+func NewUUIDv4() string {
+	return uuid.NewRandom().String()
+}
+
+// NewUUIDv7 generates a time ordered (version 7) UUID request id. The first 48
+// bits are the unix time in milliseconds and the remainder is random.
+func NewUUIDv7() string {
+	b := make([]byte, 16)
+	putTimestamp(b, time.Now())
+	rand.Read(b[6:])
+	b[6] = (b[6] & 0x0f) | 0x70 // Version 7
+	b[8] = (b[8] & 0x3f) | 0x80 // Variant is 10
+	return uuid.UUID(b).String()
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID request id (https://github.com/ulid/spec), a 26
+// character, lexicographically sortable identifier.
+func NewULID() string {
+	var b [16]byte
+	putTimestamp(b[:], time.Now())
+	rand.Read(b[6:])
+
+	// Encode the 128 bits as 26 base32 characters, 5 bits at a time, with
+	// the first character only holding the 3 most significant bits.
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// putTimestamp writes t as a 48 bit big endian count of milliseconds since the
+// unix epoch into the first 6 bytes of b.
+func putTimestamp(b []byte, t time.Time) {
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// SanitizeRequestID makes an inbound request id safe to log and forward. Any
+// characters other than letters, digits and -_.:+=/ are removed and the result
+// is truncated to MaxRequestIDLength. An empty string is returned if nothing
+// usable remains.
+func SanitizeRequestID(id string) string {
+	id = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("-_.:+=/", r):
+			return r
+		}
+		return -1
+	}, id)
+
+	if len(id) > MaxRequestIDLength {
+		id = id[:MaxRequestIDLength]
+	}
+	return id
+}
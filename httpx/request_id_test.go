@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDGenerators(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator RequestIDGenerator
+		re        *regexp.Regexp
+	}{
+		{"uuidv4", NewUUIDv4, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{"uuidv7", NewUUIDv7, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{"ulid", NewULID, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+	}
+
+	for _, tt := range tests {
+		a, b := tt.generator(), tt.generator()
+		if !tt.re.MatchString(a) {
+			t.Errorf("%s: %q does not match %s", tt.name, a, tt.re)
+		}
+		if a == b {
+			t.Errorf("%s: expected unique ids, got %q twice", tt.name, a)
+		}
+	}
+}
+
+func TestSanitizeRequestID(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"abc-123", "abc-123"},
+		{"abc 123\n", "abc123"},
+		{`"><script>`, "script"},
+		{"\n\t ", ""},
+		{strings.Repeat("a", 200), strings.Repeat("a", MaxRequestIDLength)},
+	}
+
+	for _, tt := range tests {
+		if got, want := SanitizeRequestID(tt.in), tt.out; got != want {
+			t.Errorf("SanitizeRequestID(%q) => %q; want %q", tt.in, got, want)
+		}
+	}
+}
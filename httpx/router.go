@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync/atomic"
+	"time"
 
 	"context"
 
@@ -63,7 +65,7 @@ func (r *Router) Path(path string) *Route {
 // Caches the routes so we have access to the original path template.
 func (r *Router) getOrCreateRoute(muxRoute *mux.Route, pathTpl string) *Route {
 	if route, ok := r.routes[muxRoute]; !ok {
-		route = &Route{muxRoute, pathTpl}
+		route = &Route{route: muxRoute, pathTpl: pathTpl}
 		r.routes[muxRoute] = route
 	} else if pathTpl != "" {
 		route.pathTpl = pathTpl
@@ -104,9 +106,29 @@ func (r *Router) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, re
 	route, h, vars := r.Handler(req)
 	ctx = WithVars(ctx, vars)
 	ctx = WithRoute(ctx, route)
+	if rec, ok := ctx.Value(routeRecorderKey).(*routeRecorder); ok && route != nil {
+		rec.route.Store(route)
+	}
+	if wrap, ok := ctx.Value(routeWrapperKey).(RouteWrapper); ok && wrap != nil {
+		// Only the outermost Router wraps its Handler.
+		ctx = context.WithValue(ctx, routeWrapperKey, RouteWrapper(nil))
+		h = wrap(route, h)
+	}
 	return h.ServeHTTPContext(ctx, w, req)
 }
 
+// RouteWrapper wraps the Handler of the Route matched by a Router. The Route
+// is nil when no Route matched.
+type RouteWrapper func(route *Route, h Handler) Handler
+
+// WithRouteWrapper returns a copy of ctx in which the next Router to serve the
+// request wraps the Handler of the Route it matches with wrap, for middleware
+// that wrap a Router and configure themselves per Route, without matching the
+// request again.
+func WithRouteWrapper(ctx context.Context, wrap RouteWrapper) context.Context {
+	return context.WithValue(ctx, routeWrapperKey, wrap)
+}
+
 // Vars extracts the route vars from a context.Context.
 func Vars(ctx context.Context) map[string]string {
 	vars, ok := ctx.Value(varsKey).(map[string]string)
@@ -127,12 +149,43 @@ func WithRoute(ctx context.Context, r *Route) context.Context {
 	return context.WithValue(ctx, routeKey, r)
 }
 
+// WithRouteRecorder returns a copy of ctx in which Routers record the Route they
+// match, for middleware that wrap a Router and need its Route once it's done.
+// See RecordedRoute.
+func WithRouteRecorder(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routeRecorderKey).(*routeRecorder); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routeRecorderKey, &routeRecorder{})
+}
+
+// RecordedRoute returns the Route recorded in a context returned by
+// WithRouteRecorder. When Routers are nested, it's the Route of the innermost
+// one. Nil is returned if no Route matched.
+func RecordedRoute(ctx context.Context) *Route {
+	rec, ok := ctx.Value(routeRecorderKey).(*routeRecorder)
+	if !ok {
+		return nil
+	}
+	return rec.route.Load()
+}
+
+// routeRecorder holds the Route matched by a Router. It's set and read from
+// different goroutines when handlers time out.
+type routeRecorder struct {
+	route atomic.Pointer[Route]
+}
+
 // Route wraps a mux.Route.
 type Route struct {
 	route *mux.Route
 
 	// Path template for this route, if any.
 	pathTpl string
+
+	// Timeout for requests to this route, if set.
+	timeout    time.Duration
+	hasTimeout bool
 }
 
 // RouteFromContext extracts the current Route from a context.Context.
@@ -182,6 +235,22 @@ func (r *Route) URLPath(pairs ...string) (*url.URL, error) {
 	return r.route.URLPath(pairs...)
 }
 
+// Timeout sets the time limit for requests to this route, which takes
+// precedence over the default used by middleware.RouteTimeout. A zero or
+// negative duration disables the time limit, which is useful for streaming
+// endpoints.
+func (r *Route) Timeout(d time.Duration) *Route {
+	r.timeout = d
+	r.hasTimeout = true
+	return r
+}
+
+// GetTimeout returns the time limit for requests to this route, and whether
+// one was set.
+func (r *Route) GetTimeout() (time.Duration, bool) {
+	return r.timeout, r.hasTimeout
+}
+
 // Returns the path template for this route, if any.
 func (r *Route) GetPathTemplate() string {
 	return r.pathTpl
@@ -126,3 +126,28 @@ func newRequest(method, path string, body io.Reader) *http.Request {
 
 	return req
 }
+
+func TestRecordedRoute(t *testing.T) {
+	inner := NewRouter()
+	innerRoute := inner.HandleFunc("/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}).Methods("GET")
+
+	outer := NewRouter()
+	outer.Handle("/users/{id}", inner)
+
+	ctx := WithRouteRecorder(context.Background())
+	if err := outer.ServeHTTPContext(ctx, httptest.NewRecorder(), newRequest("GET", "/users/1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := RecordedRoute(ctx), innerRoute; got != want {
+		t.Errorf("got %v; expected the route of the inner router", got)
+	}
+
+	ctx = WithRouteRecorder(context.Background())
+	outer.ServeHTTPContext(ctx, httptest.NewRecorder(), newRequest("GET", "/missing", nil))
+	if route := RecordedRoute(ctx); route != nil {
+		t.Errorf("got %v; expected no route", route)
+	}
+}
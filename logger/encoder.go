@@ -0,0 +1,468 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/remind101/pkg/redact"
+)
+
+// Format selects how a Logger returned by NewWithFormat writes messages.
+type Format string
+
+const (
+	// FormatText is the "status=level msg key=value" format of New.
+	FormatText Format = "text"
+
+	// FormatJSON writes a JSON object per line, see JSONEncoder.
+	FormatJSON Format = "json"
+
+	// FormatLogfmt writes escaped logfmt, see LogfmtEncoder.
+	FormatLogfmt Format = "logfmt"
+)
+
+// ParseFormat parses a Format: text, json or logfmt.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatLogfmt:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format: %s", s)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, with ParseFormat.
+func (f *Format) UnmarshalText(b []byte) error {
+	parsed, err := ParseFormat(string(b))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// NewWithFormat returns a Logger that writes messages to w in the format f,
+// at the level read from lv. An empty format is FormatText.
+func NewWithFormat(w io.Writer, f Format, lv *LevelVar) Logger {
+	switch f {
+	case FormatJSON:
+		return NewWithEncoder(w, JSONEncoder{}, lv)
+	case FormatLogfmt:
+		return NewWithEncoder(w, LogfmtEncoder{}, lv)
+	}
+	return NewWithLevelVar(log.New(w, "", 0), lv)
+}
+
+// Entry is a message, as given to an Encoder.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+
+	// Pairs are the key value pairs of the message, those given to With
+	// first. A trailing key without a value is given the BadKey key.
+	Pairs []interface{}
+}
+
+// BadKey is the key of values without a key, like the last of an odd number
+// of pairs.
+const BadKey = "!BADKEY"
+
+// Encoder encodes messages written by a Logger returned by NewWithEncoder.
+type Encoder interface {
+	// Encode appends e, followed by a newline, to buf.
+	Encode(buf *bytes.Buffer, e Entry)
+}
+
+// NewWithEncoder returns a Logger that writes messages encoded with enc to w,
+// at the level read from lv, or DefaultLogLevel if lv is nil. Each message is
+// written with a single call to w.Write. The values of the pairs are redacted
+// with redact.Default before they're encoded.
+func NewWithEncoder(w io.Writer, enc Encoder, lv *LevelVar) Logger {
+	if lv == nil {
+		lv = NewLevelVar(DefaultLogLevel)
+	}
+	return &encoderLogger{
+		out:      &syncWriter{w: w},
+		enc:      enc,
+		levelVar: lv,
+	}
+}
+
+// encoderLogger is an implementation of the Logger interface that writes
+// messages encoded by an Encoder.
+type encoderLogger struct {
+	out      *syncWriter
+	enc      Encoder
+	levelVar *LevelVar
+	ctxPairs []interface{}
+}
+
+func (l *encoderLogger) With(pairs ...interface{}) Logger {
+	return &encoderLogger{
+		out:      l.out,
+		enc:      l.enc,
+		levelVar: l.levelVar,
+		ctxPairs: append(append([]interface{}{}, l.ctxPairs...), pairs...),
+	}
+}
+
+func (l *encoderLogger) enabled(level Level) bool {
+	return level <= l.levelVar.Level()
+}
+
+func (l *encoderLogger) Log(level Level, msg string, pairs ...interface{}) {
+	if l.enabled(level) {
+		l.write(level, msg, pairs)
+	}
+}
+
+func (l *encoderLogger) write(level Level, msg string, pairs []interface{}) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.Reset()
+
+	l.enc.Encode(buf, Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Pairs:   redact.Default.Pairs(append(l.ctxPairs[:len(l.ctxPairs):len(l.ctxPairs)], pairs...)),
+	})
+	l.out.Write(buf.Bytes())
+}
+
+func (l *encoderLogger) Debug(msg string, pairs ...interface{}) { l.Log(DEBUG, msg, pairs...) }
+func (l *encoderLogger) Info(msg string, pairs ...interface{})  { l.Log(INFO, msg, pairs...) }
+func (l *encoderLogger) Warn(msg string, pairs ...interface{})  { l.Log(WARN, msg, pairs...) }
+func (l *encoderLogger) Error(msg string, pairs ...interface{}) { l.Log(ERROR, msg, pairs...) }
+
+var bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// syncWriter serializes writes, shared by a Logger and those returned by its
+// With.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// eachPair calls fn with the key value pairs, see Entry.Pairs.
+func eachPair(pairs []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(pairs); i += 2 {
+		if i+1 == len(pairs) {
+			fn(BadKey, pairs[i])
+			return
+		}
+		key, ok := pairs[i].(string)
+		if !ok {
+			key = fmt.Sprint(pairs[i])
+		}
+		fn(key, pairs[i+1])
+	}
+}
+
+// pairKey returns the key of a pair in the output of the encoders: the time,
+// level and msg keys are prefixed with "fields.", so that they aren't
+// duplicated.
+func pairKey(key string) string {
+	switch key {
+	case "time", "level", "msg":
+		return "fields." + key
+	}
+	return key
+}
+
+// methodString returns the result of the Error or String method of v, called
+// by fn. Like fmt, a nil pointer whose method panics is nil, and other panics
+// are formatted in the string.
+func methodString(v interface{}, method string, fn func() string) (s string, isNil bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				s, isNil = "", true
+				return
+			}
+			s = fmt.Sprintf("%%!v(PANIC=%s method: %v)", method, r)
+		}
+	}()
+	return fn(), false
+}
+
+// JSONEncoder encodes messages as a JSON object per line, with the time,
+// level and msg keys, followed by the pairs, whose time, level and msg keys
+// are prefixed with "fields.":
+//
+//	{"time":"2024-01-02T15:04:05Z","level":"info","msg":"started","port":8080}
+//
+// Errors are encoded as their message, durations as strings like "1.5s", times
+// in RFC 3339 and fmt.Stringers as their String. Other values, like maps and
+// structs, are encoded with encoding/json.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(buf *bytes.Buffer, e Entry) {
+	buf.WriteString(`{"time":`)
+	appendJSONString(buf, e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	appendJSONString(buf, FormatLevel(e.Level))
+	buf.WriteString(`,"msg":`)
+	appendJSONString(buf, e.Message)
+	eachPair(e.Pairs, func(key string, value interface{}) {
+		buf.WriteByte(',')
+		appendJSONString(buf, pairKey(key))
+		buf.WriteByte(':')
+		appendJSONValue(buf, value)
+	})
+	buf.WriteString("}\n")
+}
+
+func appendJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		appendJSONString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case int32:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case uint32:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case float64:
+		appendJSONFloat(buf, v)
+	case float32:
+		appendJSONFloat(buf, float64(v))
+	case []byte:
+		appendJSONString(buf, string(v))
+	case time.Duration:
+		appendJSONString(buf, v.String())
+	case time.Time:
+		appendJSONString(buf, v.Format(time.RFC3339Nano))
+	case error:
+		appendJSONMethod(buf, v, "Error", v.Error)
+	case json.Marshaler:
+		appendJSONMarshal(buf, v)
+	case fmt.Stringer:
+		appendJSONMethod(buf, v, "String", v.String)
+	default:
+		appendJSONMarshal(buf, v)
+	}
+}
+
+func appendJSONMethod(buf *bytes.Buffer, v interface{}, method string, fn func() string) {
+	s, isNil := methodString(v, method, fn)
+	if isNil {
+		buf.WriteString("null")
+		return
+	}
+	appendJSONString(buf, s)
+}
+
+func appendJSONFloat(buf *bytes.Buffer, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+		return
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// appendJSONMarshal encodes v with encoding/json, or its fmt representation
+// if it can't be.
+func appendJSONMarshal(buf *bytes.Buffer, v interface{}) {
+	b, err := marshalJSON(v)
+	if err != nil {
+		appendJSONString(buf, fmt.Sprintf("%+v", v))
+		return
+	}
+	buf.Write(b)
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string. Invalid UTF-8 is replaced by
+// U+FFFD.
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case c == '\n':
+				buf.WriteString(`\n`)
+			case c == '\r':
+				buf.WriteString(`\r`)
+			case c == '\t':
+				buf.WriteString(`\t`)
+			case c < 0x20 || c == 0x7f:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			default:
+				buf.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(`\ufffd`)
+		} else {
+			buf.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.WriteByte('"')
+}
+
+// LogfmtEncoder encodes messages as logfmt, with the time, level and msg
+// keys, followed by the pairs, prefixed like JSONEncoder:
+//
+//	time=2024-01-02T15:04:05Z level=info msg="server started" port=8080
+//
+// Values with spaces, quotes, equal signs or control characters are quoted and
+// escaped, so each message stays on one line. Values are formatted like
+// JSONEncoder, with maps and structs encoded as JSON. Invalid characters in
+// keys are replaced by underscores.
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(buf *bytes.Buffer, e Entry) {
+	buf.WriteString("time=")
+	buf.WriteString(e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(FormatLevel(e.Level))
+	buf.WriteString(" msg=")
+	appendLogfmtString(buf, e.Message)
+	eachPair(e.Pairs, func(key string, value interface{}) {
+		buf.WriteByte(' ')
+		appendLogfmtKey(buf, pairKey(key))
+		buf.WriteByte('=')
+		appendLogfmtString(buf, logfmtValue(value))
+	})
+	buf.WriteByte('\n')
+}
+
+// logfmtValue formats v as a string, before it's quoted.
+func logfmtValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case []byte:
+		return string(v)
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return logfmtMethod(v, "Error", v.Error)
+	case json.Marshaler:
+		b, err := marshalJSON(v)
+		if err != nil {
+			return fmt.Sprintf("%+v", v)
+		}
+		return string(b)
+	case fmt.Stringer:
+		return logfmtMethod(v, "String", v.String)
+	}
+
+	b, err := marshalJSON(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(b)
+}
+
+func logfmtMethod(v interface{}, method string, fn func() string) string {
+	s, isNil := methodString(v, method, fn)
+	if isNil {
+		return "null"
+	}
+	return s
+}
+
+func appendLogfmtKey(buf *bytes.Buffer, key string) {
+	if key == "" {
+		buf.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f || r == utf8.RuneError {
+			buf.WriteByte('_')
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// appendLogfmtString appends s, quoted if needed.
+func appendLogfmtString(buf *bytes.Buffer, s string) {
+	if !needsQuote(s) {
+		buf.WriteString(s)
+		return
+	}
+	appendJSONString(buf, s)
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/redact"
+)
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestEncoders(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		msg          string
+		pairs        []interface{}
+		json, logfmt string
+	}{
+		{
+			"started", []interface{}{"port", 8080, "tls", true},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"started","port":8080,"tls":true}`,
+			`time=2024-01-02T15:04:05Z level=info msg=started port=8080 tls=true`,
+		},
+		{
+			"two\nlines", []interface{}{"quote", `say "hi"`, "empty", ""},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"two\nlines","quote":"say \"hi\"","empty":""}`,
+			`time=2024-01-02T15:04:05Z level=info msg="two\nlines" quote="say \"hi\"" empty=""`,
+		},
+		{
+			"failed", []interface{}{"err", errors.New("boom: a=b"), "took", 1500 * time.Millisecond, "at", now},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"failed","err":"boom: a=b","took":"1.5s","at":"2024-01-02T15:04:05Z"}`,
+			`time=2024-01-02T15:04:05Z level=info msg=failed err="boom: a=b" took=1.5s at=2024-01-02T15:04:05Z`,
+		},
+		{
+			"nested", []interface{}{"user", map[string]interface{}{"id": 1, "tags": []string{"a", "<b>"}}, "s", stringer{}, "nil", nil},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"nested","user":{"id":1,"tags":["a","<b>"]},"s":"stringer","nil":null}`,
+			`time=2024-01-02T15:04:05Z level=info msg=nested user="{\"id\":1,\"tags\":[\"a\",\"<b>\"]}" s=stringer nil=null`,
+		},
+		{
+			"typed nil", []interface{}{"url", (*url.URL)(nil), "err", (*url.Error)(nil)},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"typed nil","url":null,"err":null}`,
+			`time=2024-01-02T15:04:05Z level=info msg="typed nil" url=null err=null`,
+		},
+		{
+			"reserved", []interface{}{"msg", "hi", "level", 1, "time", "now"},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"reserved","fields.msg":"hi","fields.level":1,"fields.time":"now"}`,
+			`time=2024-01-02T15:04:05Z level=info msg=reserved fields.msg=hi fields.level=1 fields.time=now`,
+		},
+		{
+			"odd", []interface{}{"bad key", 1, "trailing"},
+			`{"time":"2024-01-02T15:04:05Z","level":"info","msg":"odd","bad key":1,"!BADKEY":"trailing"}`,
+			`time=2024-01-02T15:04:05Z level=info msg=odd bad_key=1 !BADKEY=trailing`,
+		},
+	}
+
+	for _, tt := range tests {
+		e := Entry{Time: now, Level: INFO, Message: tt.msg, Pairs: tt.pairs}
+		for _, enc := range []struct {
+			Encoder
+			want string
+		}{
+			{JSONEncoder{}, tt.json},
+			{LogfmtEncoder{}, tt.logfmt},
+		} {
+			buf := new(bytes.Buffer)
+			enc.Encode(buf, e)
+			if got, want := buf.String(), enc.want+"\n"; got != want {
+				t.Errorf("%T => %s; want %s", enc.Encoder, got, want)
+			}
+		}
+
+		buf := new(bytes.Buffer)
+		JSONEncoder{}.Encode(buf, e)
+		if !json.Valid(buf.Bytes()) {
+			t.Errorf("invalid JSON: %s", buf)
+		}
+	}
+}
+
+func TestNewWithFormat(t *testing.T) {
+	b := new(bytes.Buffer)
+	lv := NewLevelVar(INFO)
+	l := NewWithFormat(b, FormatJSON, lv).With("request_id", "abc")
+
+	l.Debug("hidden")
+	l.Info("message", "count", 1)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	for k, v := range map[string]interface{}{"level": "info", "msg": "message", "request_id": "abc", "count": 1.0} {
+		if got, want := m[k], v; got != want {
+			t.Errorf("%s => %v; want %v", k, got, want)
+		}
+	}
+
+	b.Reset()
+	NewWithFormat(b, FormatLogfmt, lv).Info("message", "key", "a value")
+	if got, want := b.String(), ` level=info msg=message key="a value"`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("Logfmt Logger => %q; want suffix %q", got, want)
+	}
+
+	b.Reset()
+	NewWithFormat(b, "", lv).Info("message", "key", "value")
+	if got, want := b.String(), "status=info message key=value\n"; got != want {
+		t.Errorf("Text Logger => %q; want %q", got, want)
+	}
+}
+
+func TestNewWithFormat_Redaction(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := NewWithFormat(b, FormatJSON, NewLevelVar(INFO)).With("api_key", "k3y")
+
+	l.Info("signed up", "email", "jane@example.com", "err", errors.New("Bearer abc"), "count", 1)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	for k, v := range map[string]interface{}{"api_key": redact.Mask, "email": redact.Mask, "err": redact.Mask, "count": 1.0} {
+		if got, want := m[k], v; got != want {
+			t.Errorf("%s => %v; want %v", k, got, want)
+		}
+	}
+}
@@ -5,10 +5,14 @@ package logger
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"context"
+
+	"github.com/remind101/pkg/redact"
 )
 
 type Level int
@@ -38,6 +42,18 @@ func ParseLevel(lvl string) Level {
 	}
 }
 
+// LookupLevel is like ParseLevel, but reports whether the level is valid
+// instead of defaulting to DEBUG.
+func LookupLevel(s string) (Level, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, l := range []Level{OFF, ERROR, WARN, INFO, DEBUG} {
+		if FormatLevel(l) == s {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
 func FormatLevel(level Level) string {
 	switch level {
 	case OFF:
@@ -55,6 +71,29 @@ func FormatLevel(level Level) string {
 	}
 }
 
+// LevelVar is a Level that can be changed at runtime. It is safe for
+// concurrent use.
+type LevelVar struct {
+	v int32
+}
+
+// NewLevelVar returns a LevelVar set to l.
+func NewLevelVar(l Level) *LevelVar {
+	lv := new(LevelVar)
+	lv.Set(l)
+	return lv
+}
+
+// Level returns the current level.
+func (lv *LevelVar) Level() Level {
+	return Level(atomic.LoadInt32(&lv.v))
+}
+
+// Set changes the level.
+func (lv *LevelVar) Set(l Level) {
+	atomic.StoreInt32(&lv.v, int32(l))
+}
+
 // Logger represents a structured leveled logger.
 type Logger interface {
 	Debug(msg string, pairs ...interface{})
@@ -75,6 +114,9 @@ type logger struct {
 	Level
 	*log.Logger
 	ctxPairs []interface{} // Contextual key value pairs that will be prepended to the log message.
+
+	// levelVar, when set, takes precedence over Level.
+	levelVar *LevelVar
 }
 
 // New wraps the log.Logger to implement the Logger interface.
@@ -86,35 +128,58 @@ func New(l *log.Logger, ll Level) Logger {
 	}
 }
 
+// NewWithLevelVar is like New, but the level is read from lv each time a
+// message is logged, so that it can be changed at runtime.
+func NewWithLevelVar(l *log.Logger, lv *LevelVar) Logger {
+	return &logger{
+		Logger:   l,
+		ctxPairs: []interface{}{},
+		levelVar: lv,
+	}
+}
+
 // With returns a new logger with the given key value pairs added to each log message.
 func (l *logger) With(pairs ...interface{}) Logger {
 	return &logger{
 		Logger:   l.Logger,
 		Level:    l.Level,
 		ctxPairs: append(l.ctxPairs, pairs...),
+		levelVar: l.levelVar,
 	}
 }
 
 // Log logs the pairs in logfmt. It will treat consecutive arguments as a key
-// value pair. Given the input:
+// value pair, with the values redacted with redact.Default. Given the input:
 func (l *logger) Log(level Level, msg string, pairs ...interface{}) {
-	if level <= l.Level {
-		msg = "status=" + FormatLevel(level) + " " + msg
-		m := l.message(pairs...)
-		l.Println(msg, m)
+	if l.enabled(level) {
+		l.write(level, msg, pairs)
 	}
 }
 
+func (l *logger) write(level Level, msg string, pairs []interface{}) {
+	msg = "status=" + FormatLevel(level) + " " + msg
+	m := l.message(pairs...)
+	l.Println(msg, m)
+}
+
+func (l *logger) enabled(level Level) bool {
+	max := l.Level
+	if l.levelVar != nil {
+		max = l.levelVar.Level()
+	}
+	return level <= max
+}
+
 func (l *logger) Debug(msg string, pairs ...interface{}) { l.Log(DEBUG, msg, pairs...) }
 func (l *logger) Info(msg string, pairs ...interface{})  { l.Log(INFO, msg, pairs...) }
 func (l *logger) Error(msg string, pairs ...interface{}) { l.Log(ERROR, msg, pairs...) }
 func (l *logger) Warn(msg string, pairs ...interface{})  { l.Log(WARN, msg, pairs...) }
 
 func (l *logger) message(pairs ...interface{}) string {
-	pairs = append(l.ctxPairs, pairs...)
+	pairs = redact.Default.Pairs(append(l.ctxPairs, pairs...))
 
 	if len(pairs) == 1 {
-		return fmt.Sprintf("%v", pairs[0])
+		return redact.Default.Scrub(fmt.Sprintf("%v", pairs[0]))
 	}
 
 	var parts []string
@@ -130,7 +195,7 @@ func (l *logger) message(pairs ...interface{}) string {
 		//
 		//	key=value message
 		if len(pairs) == i+1 {
-			parts = append(parts, fmt.Sprintf("%v", pairs[i]))
+			parts = append(parts, redact.Default.Scrub(fmt.Sprintf("%v", pairs[i])))
 		} else {
 			parts = append(parts, fmt.Sprintf("%s=%v", pairs[i], pairs[i+1]))
 		}
@@ -144,33 +209,42 @@ func WithLogger(ctx context.Context, l Logger) context.Context {
 	return context.WithValue(ctx, loggerKey, l)
 }
 
-// FromContext returns a log.Logger from the context.
+// FromContext returns a log.Logger from the context. An *slog.Logger inserted
+// with WithSlog is returned as a Logger.
 func FromContext(ctx context.Context) (Logger, bool) {
-	l, ok := ctx.Value(loggerKey).(Logger)
-	return l, ok
+	switch l := ctx.Value(loggerKey).(type) {
+	case Logger:
+		return l, true
+	case *slog.Logger:
+		return &slogLogger{handler: l.Handler()}, true
+	}
+	return nil, false
 }
 
 func Info(ctx context.Context, msg string, pairs ...interface{}) {
-	withLogger(ctx, func(l Logger) {
-		l.Info(msg, pairs...)
-	})
+	logCtx(ctx, INFO, msg, pairs)
 }
 
 func Debug(ctx context.Context, msg string, pairs ...interface{}) {
-	withLogger(ctx, func(l Logger) {
-		l.Debug(msg, pairs...)
-	})
+	logCtx(ctx, DEBUG, msg, pairs)
 }
 
 func Warn(ctx context.Context, msg string, pairs ...interface{}) {
-	withLogger(ctx, func(l Logger) {
-		l.Warn(msg, pairs...)
-	})
+	logCtx(ctx, WARN, msg, pairs)
 }
 
 func Error(ctx context.Context, msg string, pairs ...interface{}) {
+	logCtx(ctx, ERROR, msg, pairs)
+}
+
+// logCtx logs a message at level with the logger in ctx, and the trace pairs
+// of ctx, which are only read when the level is enabled.
+func logCtx(ctx context.Context, level Level, msg string, pairs []interface{}) {
 	withLogger(ctx, func(l Logger) {
-		l.Error(msg, pairs...)
+		if e, ok := l.(enabler); ok && !e.enabled(level) {
+			return
+		}
+		logAt(l, level, msg, withTracePairs(ctx, pairs)...)
 	})
 }
 
@@ -186,4 +260,5 @@ type key int
 
 const (
 	loggerKey key = iota
+	spanRecorderKey
 )
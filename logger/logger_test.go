@@ -2,7 +2,9 @@ package logger
 
 import (
 	"bytes"
+	"io"
 	"log"
+	"net/url"
 	"testing"
 
 	"context"
@@ -21,6 +23,8 @@ func TestLogger(t *testing.T) {
 		{[]interface{}{"count", 1}, "status=info message count=1\n"},
 		{[]interface{}{"b", 1, "a", 1}, "status=info message b=1 a=1\n"},
 		{[]interface{}{}, "status=info message \n"},
+		{[]interface{}{"password", "hunter2", "email", "jane@example.com"}, "status=info message password=[REDACTED] email=[REDACTED]\n"},
+		{[]interface{}{"token=abc"}, "status=info message token=[REDACTED]\n"},
 	}
 
 	for _, tt := range tests {
@@ -53,6 +57,20 @@ func TestLogLevel(t *testing.T) {
 	}
 }
 
+func TestLevelVar(t *testing.T) {
+	b := new(bytes.Buffer)
+	lv := NewLevelVar(WARN)
+	l := NewWithLevelVar(log.New(b, "", 0), lv).With("request_id", "abc")
+
+	l.Info("before")
+	lv.Set(INFO)
+	l.Info("after")
+
+	if got, want := b.String(), "status=info after request_id=abc\n"; got != want {
+		t.Fatalf("LevelVar Logger => %q; want %q", got, want)
+	}
+}
+
 func TestWith(t *testing.T) {
 	b := new(bytes.Buffer)
 	l := New(log.New(b, "", 0), INFO)
@@ -83,3 +101,32 @@ func TestWithoutContextLogger(t *testing.T) {
 		t.Fatalf("Without Context Logger => %q; want %q", got, want)
 	}
 }
+
+func BenchmarkLogger(b *testing.B) {
+	l := New(log.New(io.Discard, "", log.LstdFlags), INFO)
+	pairs := []interface{}{"request_id", "8a4c5d6e", "path", "/users/1?page=2", "user", "jane", "status", 200}
+
+	b.Run("Info", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info("request", pairs...)
+		}
+	})
+
+	b.Run("Debug", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Debug("request", pairs...)
+		}
+	})
+}
+
+func TestLogger_TypedNil(t *testing.T) {
+	var u *url.URL
+
+	b := new(bytes.Buffer)
+	New(log.New(b, "", 0), INFO).Info("message", "url", u)
+	if got, want := b.String(), "status=info message url=<nil>\n"; got != want {
+		t.Fatalf("Info => %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+package logger
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NameKey is the key of the name of the Loggers returned by Named.
+const NameKey = "logger"
+
+// LevelRule sets the level of the Loggers returned by Named with a name
+// matching Pattern, see Levels.
+type LevelRule struct {
+	Pattern string
+	Level   Level
+}
+
+// String formats the rule as pattern=level.
+func (r LevelRule) String() string {
+	return r.Pattern + "=" + FormatLevel(r.Level)
+}
+
+// LevelRules are parsed from comma separated pattern=level pairs, like
+// "client.*=debug,db=warn".
+type LevelRules []LevelRule
+
+// ParseLevelRules parses LevelRules, like "client.*=debug,db=warn".
+func ParseLevelRules(s string) (LevelRules, error) {
+	var rules LevelRules
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		pattern, lvl, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid log level rule: %s", part)
+		}
+		level, ok := LookupLevel(lvl)
+		if !ok {
+			return nil, fmt.Errorf("invalid log level: %s", lvl)
+		}
+		pattern = strings.TrimSpace(pattern)
+		if err := checkPattern(pattern); err != nil {
+			return nil, err
+		}
+		rules = append(rules, LevelRule{Pattern: pattern, Level: level})
+	}
+	return rules, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, with ParseLevelRules.
+func (r *LevelRules) UnmarshalText(b []byte) error {
+	rules, err := ParseLevelRules(string(b))
+	if err != nil {
+		return err
+	}
+	*r = rules
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r LevelRules) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// String formats the rules like ParseLevelRules parses them.
+func (r LevelRules) String() string {
+	parts := make([]string, len(r))
+	for i, rule := range r {
+		parts[i] = rule.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func checkPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("invalid log level rule: empty pattern")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid log level pattern %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// Levels holds the level rules of the Loggers returned by Named. It's safe
+// for concurrent use, so that levels can be changed at runtime.
+//
+// A pattern matches names with the syntax of path.Match, with dots as the
+// separators, so that "client.*" matches "client.redis", but not
+// "client.redis.pool". Patterns also match the names nested in those they
+// match: "client" matches "client.redis", and "client.*" matches
+// "client.redis.pool". When several patterns match a name, the most specific
+// wins: a match of the name over one of its parents, then the longest
+// pattern.
+type Levels struct {
+	mu    sync.RWMutex
+	rules LevelRules
+
+	// gen is incremented when the rules change, to invalidate the levels
+	// cached by the Loggers.
+	gen atomic.Uint64
+}
+
+// NewLevels returns Levels with the given rules.
+func NewLevels(rules ...LevelRule) *Levels {
+	ls := &Levels{}
+	ls.Replace(rules)
+	return ls
+}
+
+// DefaultLevels are the Levels of the Loggers returned by Named.
+var DefaultLevels = NewLevels()
+
+// Set sets the level of the names matching pattern.
+func (ls *Levels) Set(pattern string, level Level) error {
+	if err := checkPattern(pattern); err != nil {
+		return err
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for i, r := range ls.rules {
+		if r.Pattern == pattern {
+			ls.rules[i].Level = level
+			ls.gen.Add(1)
+			return nil
+		}
+	}
+	ls.rules = append(ls.rules, LevelRule{Pattern: pattern, Level: level})
+	ls.gen.Add(1)
+	return nil
+}
+
+// Unset removes the rule of pattern, if any.
+func (ls *Levels) Unset(pattern string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for i, r := range ls.rules {
+		if r.Pattern == pattern {
+			ls.rules = append(ls.rules[:i:i], ls.rules[i+1:]...)
+			ls.gen.Add(1)
+			return
+		}
+	}
+}
+
+// Replace replaces all the rules. Invalid patterns are ignored.
+func (ls *Levels) Replace(rules LevelRules) {
+	valid := make(LevelRules, 0, len(rules))
+	for _, r := range rules {
+		if checkPattern(r.Pattern) == nil {
+			valid = append(valid, r)
+		}
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.rules = valid
+	ls.gen.Add(1)
+}
+
+// Rules returns a copy of the rules.
+func (ls *Levels) Rules() LevelRules {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return append(LevelRules{}, ls.rules...)
+}
+
+// Level returns the level of the rule that matches name, if any.
+func (ls *Levels) Level(name string) (Level, bool) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	if len(ls.rules) == 0 {
+		return 0, false
+	}
+	for n := name; ; {
+		best := -1
+		for i, r := range ls.rules {
+			if matchName(r.Pattern, n) && (best < 0 || len(r.Pattern) >= len(ls.rules[best].Pattern)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return ls.rules[best].Level, true
+		}
+
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			return 0, false
+		}
+		n = n[:i]
+	}
+}
+
+// matchName reports whether the pattern matches the name, see Levels.
+func matchName(pattern, name string) bool {
+	ok, _ := path.Match(strings.Replace(pattern, ".", "/", -1), strings.Replace(name, ".", "/", -1))
+	return ok
+}
+
+// Named returns a Logger for a component of the program, like "client.redis",
+// that writes to DefaultLogger with the name as the NameKey pair. Its level
+// is set by the rules of DefaultLevels matching the name, which can be more
+// verbose than the level of DefaultLogger, so that debug messages can be
+// turned on for one component:
+//
+//	var log = logger.Named("client.redis")
+//	...
+//	logger.DefaultLevels.Set("client.*", logger.DEBUG)
+//
+// Without a matching rule, the level of DefaultLogger is used. DefaultLogger
+// is read when messages are logged, so Named can be called before it's set.
+func Named(name string) Logger {
+	return NewNamed(nil, name)
+}
+
+// NewNamed is like Named, but writes to l, or DefaultLogger if l is nil. When
+// l is a named Logger, the names are joined with a dot.
+func NewNamed(l Logger, name string) Logger {
+	if n, ok := l.(*namedLogger); ok {
+		return &namedLogger{
+			base:     n.base,
+			name:     n.name + "." + name,
+			ctxPairs: n.ctxPairs,
+			levels:   n.levels,
+			cache:    new(atomic.Pointer[namedLevel]),
+		}
+	}
+	return &namedLogger{
+		base:   l,
+		name:   name,
+		levels: DefaultLevels,
+		cache:  new(atomic.Pointer[namedLevel]),
+	}
+}
+
+// namedLogger is an implementation of the Logger interface returned by Named.
+type namedLogger struct {
+	base     Logger
+	name     string
+	ctxPairs []interface{}
+	levels   *Levels
+
+	// cache is the level of the name, shared with the Loggers returned by
+	// With.
+	cache *atomic.Pointer[namedLevel]
+}
+
+type namedLevel struct {
+	gen   uint64
+	level Level
+	ok    bool
+}
+
+func (l *namedLogger) With(pairs ...interface{}) Logger {
+	return &namedLogger{
+		base:     l.base,
+		name:     l.name,
+		ctxPairs: append(append([]interface{}{}, l.ctxPairs...), pairs...),
+		levels:   l.levels,
+		cache:    l.cache,
+	}
+}
+
+func (l *namedLogger) Debug(msg string, pairs ...interface{}) { l.log(DEBUG, msg, pairs) }
+func (l *namedLogger) Info(msg string, pairs ...interface{})  { l.log(INFO, msg, pairs) }
+func (l *namedLogger) Warn(msg string, pairs ...interface{})  { l.log(WARN, msg, pairs) }
+func (l *namedLogger) Error(msg string, pairs ...interface{}) { l.log(ERROR, msg, pairs) }
+
+func (l *namedLogger) logger() Logger {
+	if l.base != nil {
+		return l.base
+	}
+	return DefaultLogger
+}
+
+// level returns the level of the rule matching the name, if any.
+func (l *namedLogger) level() (Level, bool) {
+	gen := l.levels.gen.Load()
+	if c := l.cache.Load(); c != nil && c.gen == gen {
+		return c.level, c.ok
+	}
+	level, ok := l.levels.Level(l.name)
+	l.cache.Store(&namedLevel{gen: gen, level: level, ok: ok})
+	return level, ok
+}
+
+func (l *namedLogger) enabled(level Level) bool {
+	if max, ok := l.level(); ok {
+		return level <= max
+	}
+	if e, ok := l.logger().(enabler); ok {
+		return e.enabled(level)
+	}
+	return true
+}
+
+func (l *namedLogger) log(level Level, msg string, pairs []interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+	pairs = append(append([]interface{}{NameKey, l.name}, l.ctxPairs...), pairs...)
+
+	if _, ok := l.level(); !ok {
+		logAt(l.logger(), level, msg, pairs...)
+		return
+	}
+	writeAt(l.logger(), level, msg, pairs...)
+}
+
+// levelWriter is implemented by the Loggers of this package, to write a
+// message regardless of their level, when the level of a named Logger is
+// more verbose.
+type levelWriter interface {
+	write(level Level, msg string, pairs []interface{})
+}
+
+// writeAt writes a message to l at level, regardless of the level of l when
+// it's a levelWriter.
+func writeAt(l Logger, level Level, msg string, pairs ...interface{}) {
+	if w, ok := l.(levelWriter); ok {
+		w.write(level, msg, pairs)
+		return
+	}
+	logAt(l, level, msg, pairs...)
+}
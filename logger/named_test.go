@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevelRules(t *testing.T) {
+	rules, err := ParseLevelRules("client.*=debug, db=WARN,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := rules.MarshalText()
+	if got, want := string(b), "client.*=debug,db=warn"; got != want {
+		t.Fatalf("ParseLevelRules => %q; want %q", got, want)
+	}
+
+	for _, s := range []string{"client", "client=verbose", "=debug", "[=debug"} {
+		if _, err := ParseLevelRules(s); err == nil {
+			t.Errorf("ParseLevelRules(%q) => nil; want an error", s)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	ls := NewLevels(
+		LevelRule{Pattern: "client", Level: INFO},
+		LevelRule{Pattern: "client.*", Level: WARN},
+		LevelRule{Pattern: "client.redis", Level: DEBUG},
+	)
+
+	tests := []struct {
+		name  string
+		level Level
+		ok    bool
+	}{
+		{"client", INFO, true},
+		{"client.http", WARN, true},
+		{"client.redis", DEBUG, true},
+		{"client.redis.pool", DEBUG, true},
+		{"db", 0, false},
+	}
+
+	for _, tt := range tests {
+		level, ok := ls.Level(tt.name)
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("Level(%q) => %v, %v; want %v, %v", tt.name, level, ok, tt.level, tt.ok)
+		}
+	}
+
+	ls.Unset("client.redis")
+	if got, _ := ls.Level("client.redis"); got != WARN {
+		t.Fatalf("Level => %v; want %v", got, WARN)
+	}
+	if err := ls.Set("[", DEBUG); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	b := new(bytes.Buffer)
+	base := New(log.New(b, "", 0), ERROR)
+
+	defer DefaultLevels.Replace(nil)
+
+	l := NewNamed(NewNamed(base, "client"), "redis").With("db", 0)
+
+	l.Debug("hidden")
+	if got := b.String(); got != "" {
+		t.Fatalf("expected the level of the base logger to be used without a rule, got %q", got)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { l.Debug("hidden") }); allocs != 0 {
+		t.Fatalf("Debug => %v allocs; want the pairs not to be built when the level is disabled", allocs)
+	}
+
+	DefaultLevels.Set("client.*", DEBUG)
+	l.Debug("dial", "addr", "localhost")
+	if got, want := b.String(), "status=debug dial logger=client.redis db=0 addr=localhost\n"; got != want {
+		t.Fatalf("Debug => %q; want %q", got, want)
+	}
+
+	b.Reset()
+	DefaultLevels.Set("client.redis", WARN)
+	l.Info("hidden")
+	l.Warn("slow")
+	if got, want := b.String(), "status=warn slow logger=client.redis db=0\n"; got != want {
+		t.Fatalf("Warn => %q; want %q", got, want)
+	}
+}
+
+func TestNamed_DefaultLogger(t *testing.T) {
+	l := Named("worker")
+
+	b := new(bytes.Buffer)
+	defer func(l Logger) { DefaultLogger = l }(DefaultLogger)
+	DefaultLogger = NewWithHandler(slog.NewTextHandler(b, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	defer DefaultLevels.Replace(nil)
+	DefaultLevels.Set("worker", DEBUG)
+
+	l.Debug("started")
+	if got, want := b.String(), `level=DEBUG msg=started logger=worker`; !strings.Contains(got, want) {
+		t.Fatalf("Debug => %q; want it to contain %q", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/timex"
+)
+
+// SampleRule samples the messages logged at a level, see SamplerOpts.
+type SampleRule struct {
+	// First is the number of messages with the same text logged each
+	// interval before sampling starts.
+	First int
+
+	// Thereafter logs every Thereafter-th message after First. Zero drops
+	// them all until the next interval.
+	Thereafter int
+}
+
+// SamplerOpts configures a Logger returned by NewSampler.
+type SamplerOpts struct {
+	// Interval is the period over which messages are counted for the
+	// Rules. Zero means one second.
+	Interval time.Duration
+
+	// Rules sample messages by level. Messages at a level without a rule
+	// aren't sampled.
+	Rules map[Level]SampleRule
+
+	// DedupWindow, when set, drops messages with the same level, text and
+	// keys as one logged less than DedupWindow ago. Once the window is over,
+	// the number of messages dropped is logged, as "suppressed N messages".
+	DedupWindow time.Duration
+
+	// DroppedMetric is the name of the count of dropped messages, tagged
+	// with level and reason, sampled or duplicate. Zero means
+	// "logger.dropped".
+	DroppedMetric string
+}
+
+// DefaultSampleRules log the first 100 messages with the same text per
+// interval, then every 100th, at every level but ERROR.
+var DefaultSampleRules = map[Level]SampleRule{
+	WARN:  {First: 100, Thereafter: 100},
+	INFO:  {First: 100, Thereafter: 100},
+	DEBUG: {First: 100, Thereafter: 100},
+}
+
+// maxSamplerKeys bounds the number of distinct messages a sampler tracks.
+const maxSamplerKeys = 4096
+
+// NewSampler returns a Logger that samples and deduplicates the messages
+// logged to l, so that a message repeated in a loop doesn't flood the logs.
+//
+// Usage:
+//
+//	l = logger.NewSampler(l, logger.SamplerOpts{
+//		Rules:       logger.DefaultSampleRules,
+//		DedupWindow: 10 * time.Second,
+//	})
+func NewSampler(l Logger, opts SamplerOpts) Logger {
+	if opts.Interval == 0 {
+		opts.Interval = time.Second
+	}
+	if opts.DroppedMetric == "" {
+		opts.DroppedMetric = "logger.dropped"
+	}
+	return &sampler{
+		Logger: l,
+		state: &samplerState{
+			opts:   opts,
+			counts: make(map[string]*sampleCount),
+			dups:   make(map[string]*dedupEntry),
+		},
+	}
+}
+
+// sampler is an implementation of the Logger interface that samples the
+// messages logged to the wrapped Logger.
+type sampler struct {
+	Logger
+	state *samplerState
+}
+
+// samplerState is shared by a sampler and those returned by its With.
+type samplerState struct {
+	opts SamplerOpts
+
+	mu     sync.Mutex
+	counts map[string]*sampleCount
+	dups   map[string]*dedupEntry
+}
+
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+type dedupEntry struct {
+	suppressed int
+}
+
+func (s *sampler) With(pairs ...interface{}) Logger {
+	return &sampler{Logger: s.Logger.With(pairs...), state: s.state}
+}
+
+func (s *sampler) Debug(msg string, pairs ...interface{}) { s.log(DEBUG, msg, pairs) }
+func (s *sampler) Info(msg string, pairs ...interface{})  { s.log(INFO, msg, pairs) }
+func (s *sampler) Warn(msg string, pairs ...interface{})  { s.log(WARN, msg, pairs) }
+func (s *sampler) Error(msg string, pairs ...interface{}) { s.log(ERROR, msg, pairs) }
+
+func (s *sampler) enabled(level Level) bool {
+	if e, ok := s.Logger.(enabler); ok {
+		return e.enabled(level)
+	}
+	return true
+}
+
+func (s *sampler) log(level Level, msg string, pairs []interface{}) {
+	// Messages the wrapped Logger drops aren't counted.
+	if s.enabled(level) {
+		s.write(level, msg, pairs)
+	}
+}
+
+func (s *sampler) write(level Level, msg string, pairs []interface{}) {
+	if reason := s.state.drop(s.Logger, level, msg, pairs); reason != "" {
+		metrics.Count(s.state.opts.DroppedMetric, 1, map[string]string{
+			"level":  FormatLevel(level),
+			"reason": reason,
+		}, 1.0)
+		return
+	}
+	writeAt(s.Logger, level, msg, pairs...)
+}
+
+// drop returns why a message should be dropped, or "".
+func (st *samplerState) drop(l Logger, level Level, msg string, pairs []interface{}) string {
+	now := timex.Now()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.opts.DedupWindow > 0 {
+		key := dedupKey(level, msg, pairs)
+		if e, ok := st.dups[key]; ok {
+			e.suppressed++
+			return "duplicate"
+		}
+		if len(st.dups) < maxSamplerKeys {
+			st.dups[key] = &dedupEntry{}
+			time.AfterFunc(st.opts.DedupWindow, func() {
+				st.summarize(l, key, level, msg)
+			})
+		}
+	}
+
+	rule, ok := st.opts.Rules[level]
+	if !ok {
+		return ""
+	}
+
+	key := FormatLevel(level) + "\x00" + msg
+	c, ok := st.counts[key]
+	if !ok {
+		if len(st.counts) >= maxSamplerKeys {
+			st.counts = make(map[string]*sampleCount)
+		}
+		c = &sampleCount{start: now}
+		st.counts[key] = c
+	}
+	if now.Sub(c.start) >= st.opts.Interval {
+		c.start, c.n = now, 0
+	}
+	c.n++
+
+	if c.n <= rule.First {
+		return ""
+	}
+	if rule.Thereafter > 0 && (c.n-rule.First)%rule.Thereafter == 0 {
+		return ""
+	}
+	return "sampled"
+}
+
+// summarize ends the dedup window of a message, logging the number of
+// messages suppressed during it.
+func (st *samplerState) summarize(l Logger, key string, level Level, msg string) {
+	st.mu.Lock()
+	e := st.dups[key]
+	delete(st.dups, key)
+	st.mu.Unlock()
+
+	if e != nil && e.suppressed > 0 {
+		logAt(l, level, fmt.Sprintf("suppressed %d messages", e.suppressed), "message", msg)
+	}
+}
+
+// dedupKey identifies messages with the same level, text and keys.
+func dedupKey(level Level, msg string, pairs []interface{}) string {
+	keys := make([]string, 0, len(pairs)/2)
+	eachPair(pairs, func(key string, _ interface{}) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	return FormatLevel(level) + "\x00" + msg + "\x00" + strings.Join(keys, "\x00")
+}
+
+// logAt logs a message to l at level.
+func logAt(l Logger, level Level, msg string, pairs ...interface{}) {
+	switch level {
+	case ERROR:
+		l.Error(msg, pairs...)
+	case WARN:
+		l.Warn(msg, pairs...)
+	case INFO:
+		l.Info(msg, pairs...)
+	default:
+		l.Debug(msg, pairs...)
+	}
+}
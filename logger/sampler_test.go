@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/metrics/metricstest"
+	"github.com/remind101/pkg/timex"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for messages logged
+// by timers.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestSampler(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	defer func(f func() time.Time) { timex.Now = f }(timex.Now)
+	timex.Now = func() time.Time { return now }
+
+	b := new(bytes.Buffer)
+	l := NewSampler(New(log.New(b, "", 0), INFO), SamplerOpts{
+		Rules: map[Level]SampleRule{INFO: {First: 2, Thereafter: 3}},
+	})
+
+	for i := 0; i < 8; i++ {
+		l.Info("tick", "i", i)
+	}
+	l.Error("failed")
+	l.Debug("hidden")
+
+	now = now.Add(time.Second)
+	l.With("request_id", "abc").Info("tick", "i", 8)
+
+	want := "status=info tick i=0\n" +
+		"status=info tick i=1\n" +
+		"status=info tick i=4\n" +
+		"status=info tick i=7\n" +
+		"status=error failed \n" +
+		"status=info tick request_id=abc i=8\n"
+	if got := b.String(); got != want {
+		t.Fatalf("Sampler => %q; want %q", got, want)
+	}
+
+	metricstest.AssertCounter(t, reporter, "logger.dropped", map[string]string{"level": "info", "reason": "sampled"}, 4)
+}
+
+func TestSampler_Dedup(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	b := new(syncBuffer)
+	l := NewSampler(New(log.New(b, "", 0), INFO), SamplerOpts{
+		DedupWindow: 20 * time.Millisecond,
+	})
+
+	for i := 0; i < 5; i++ {
+		l.Error("connection refused", "host", "db")
+	}
+	l.Error("connection refused")
+	l.Error("connection refused", "host", "db", "port", 5432)
+
+	time.Sleep(100 * time.Millisecond)
+	l.Error("connection refused", "host", "db")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	want := []string{
+		"status=error connection refused host=db",
+		"status=error connection refused ",
+		"status=error connection refused host=db port=5432",
+		"status=error suppressed 4 messages message=connection refused",
+		"status=error connection refused host=db",
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(want, "\n") {
+		t.Fatalf("Dedup Sampler =>\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	metricstest.AssertCounter(t, reporter, "logger.dropped", map[string]string{"level": "error", "reason": "duplicate"}, 4)
+}
@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/remind101/pkg/redact"
+)
+
+// NewWithHandler returns a Logger backed by h. The levels map to
+// slog.LevelDebug, slog.LevelInfo, slog.LevelWarn and slog.LevelError.
+func NewWithHandler(h slog.Handler) Logger {
+	return &slogLogger{handler: h}
+}
+
+// Slog returns an *slog.Logger that writes to l. The Logger in the context of
+// a record is used instead of l when there is one, see NewSlogHandler.
+func Slog(l Logger) *slog.Logger {
+	if sl, ok := l.(*slogLogger); ok {
+		return slog.New(sl.handler)
+	}
+	return slog.New(NewSlogHandler(l))
+}
+
+// WithSlog inserts an *slog.Logger into the provided context. FromContext
+// returns it as a Logger.
+func WithSlog(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// SlogFromContext returns the logger in the context as an *slog.Logger, or
+// one writing to DefaultLogger.
+func SlogFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	if l, ok := FromContext(ctx); ok {
+		return Slog(l)
+	}
+	return Slog(DefaultLogger)
+}
+
+// slogLevel returns the slog.Level of a Level.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case ERROR:
+		return slog.LevelError
+	case WARN:
+		return slog.LevelWarn
+	case INFO:
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// fromSlogLevel returns the Level of an slog.Level, rounded down.
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARN
+	case level >= slog.LevelInfo:
+		return INFO
+	}
+	return DEBUG
+}
+
+// slogLogger is an implementation of the Logger interface backed by an
+// slog.Handler.
+type slogLogger struct {
+	handler slog.Handler
+}
+
+func (l *slogLogger) With(pairs ...interface{}) Logger {
+	return &slogLogger{handler: slog.New(l.handler).With(redact.Default.Pairs(pairs)...).Handler()}
+}
+
+func (l *slogLogger) Debug(msg string, pairs ...interface{}) { l.log(DEBUG, msg, pairs) }
+func (l *slogLogger) Info(msg string, pairs ...interface{})  { l.log(INFO, msg, pairs) }
+func (l *slogLogger) Warn(msg string, pairs ...interface{})  { l.log(WARN, msg, pairs) }
+func (l *slogLogger) Error(msg string, pairs ...interface{}) { l.log(ERROR, msg, pairs) }
+
+func (l *slogLogger) enabled(level Level) bool {
+	return l.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (l *slogLogger) log(level Level, msg string, pairs []interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+
+	// Skip runtime.Callers, output, log and the level method, so the source
+	// of the record is the caller of the Logger.
+	l.output(4, level, msg, pairs)
+}
+
+func (l *slogLogger) write(level Level, msg string, pairs []interface{}) {
+	// Skip runtime.Callers, output, write, writeAt and the log and level
+	// methods of the named Logger.
+	l.output(6, level, msg, pairs)
+}
+
+func (l *slogLogger) output(skip int, level Level, msg string, pairs []interface{}) {
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+
+	r := slog.NewRecord(time.Now(), slogLevel(level), msg, pcs[0])
+	r.Add(redact.Default.Pairs(pairs)...)
+	l.handler.Handle(context.Background(), r)
+}
+
+// NewSlogHandler returns an slog.Handler that writes records to the Logger in
+// their context, see FromContext, or to l when there is none. This way, lines
+// logged with slog within a request carry the pairs of the request's Logger,
+// like request_id, and the TraceCorrelation pairs of the context:
+//
+//	slog.SetDefault(slog.New(logger.NewSlogHandler(logger.DefaultLogger)))
+//	...
+//	slog.InfoContext(ctx, "cache miss", "key", key)
+//
+// Attributes in groups are written with keys prefixed by the group names,
+// like "group.key".
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+type slogHandler struct {
+	logger Logger
+	pairs  []interface{}
+	prefix string
+}
+
+// enabler is implemented by the Loggers of this package, to tell whether a
+// message at a level would be written.
+type enabler interface {
+	enabled(Level) bool
+}
+
+func (h *slogHandler) target(ctx context.Context) Logger {
+	if l, ok := FromContext(ctx); ok {
+		return l
+	}
+	return h.logger
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if e, ok := h.target(ctx).(enabler); ok {
+		return e.enabled(fromSlogLevel(level))
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	pairs := withTracePairs(ctx, h.pairs[:len(h.pairs):len(h.pairs)])
+	r.Attrs(func(a slog.Attr) bool {
+		pairs = appendAttr(pairs, h.prefix, a)
+		return true
+	})
+
+	logAt(h.target(ctx), fromSlogLevel(r.Level), r.Message, pairs...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	pairs := append([]interface{}{}, h.pairs...)
+	for _, a := range attrs {
+		pairs = appendAttr(pairs, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger, pairs: pairs, prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, pairs: h.pairs, prefix: h.prefix + name + "."}
+}
+
+// appendAttr appends a as key value pairs, flattening groups.
+func appendAttr(pairs []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return pairs
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			pairs = appendAttr(pairs, prefix, ga)
+		}
+		return pairs
+	}
+	return append(pairs, prefix+a.Key, a.Value.Any())
+}
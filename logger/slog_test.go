@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestSlogHandler(b *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(b, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+func TestNewWithHandler(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := NewWithHandler(newTestSlogHandler(b)).With("request_id", "abc")
+
+	l.Debug("hidden")
+	l.Info("message", "count", 1)
+	l.Error("failed", "trailing")
+
+	want := "level=INFO msg=message request_id=abc count=1\n" +
+		"level=ERROR msg=failed request_id=abc !BADKEY=trailing\n"
+	if got := b.String(); got != want {
+		t.Fatalf("Slog Logger => %q; want %q", got, want)
+	}
+}
+
+func TestNewSlogHandler(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := New(log.New(b, "", 0), INFO)
+	sl := slog.New(NewSlogHandler(l))
+
+	sl.Debug("hidden")
+	sl.With("a", 1).WithGroup("g").Info("message", "b", 2, slog.Group("h", "c", 3))
+
+	if got, want := b.String(), "status=info message a=1 g.b=2 g.h.c=3\n"; got != want {
+		t.Fatalf("Slog Handler => %q; want %q", got, want)
+	}
+	if sl.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected debug to be disabled")
+	}
+}
+
+func TestNewSlogHandler_ContextLogger(t *testing.T) {
+	b := new(bytes.Buffer)
+	ctx := WithLogger(context.Background(), New(log.New(b, "", 0), DEBUG).With("request_id", "abc"))
+	sl := slog.New(NewSlogHandler(New(log.New(new(bytes.Buffer), "", 0), ERROR)))
+
+	sl.DebugContext(ctx, "message", "key", "value")
+
+	if got, want := b.String(), "status=debug message request_id=abc key=value\n"; got != want {
+		t.Fatalf("Slog Handler => %q; want %q", got, want)
+	}
+}
+
+func TestWithSlog(t *testing.T) {
+	b := new(bytes.Buffer)
+	ctx := WithSlog(context.Background(), slog.New(newTestSlogHandler(b)).With("request_id", "abc"))
+
+	Info(ctx, "message", "key", "value")
+	if got, want := b.String(), "level=INFO msg=message request_id=abc key=value\n"; got != want {
+		t.Fatalf("Context Slog Logger => %q; want %q", got, want)
+	}
+
+	b.Reset()
+	SlogFromContext(ctx).Info("direct")
+	if got, want := b.String(), "level=INFO msg=direct request_id=abc\n"; got != want {
+		t.Fatalf("SlogFromContext => %q; want %q", got, want)
+	}
+}
+
+func TestNewWithHandler_Source(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := NewWithHandler(slog.NewTextHandler(b, &slog.HandlerOptions{AddSource: true}))
+	l.Info("message")
+
+	if got := b.String(); !strings.Contains(got, "slog_test.go") {
+		t.Errorf("expected the source to be the caller, got %q", got)
+	}
+}
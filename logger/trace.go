@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/remind101/pkg/tracing/tracecontext"
+)
+
+// TraceFields configures the pairs that correlate messages logged with a
+// context, see Info, with the active span of the context.
+type TraceFields struct {
+	// TraceIDKey and SpanIDKey are the keys of the trace and span ids. Ids
+	// with an empty key aren't logged.
+	TraceIDKey, SpanIDKey string
+
+	// Datadog formats the ids as decimal numbers, using the lower 64 bits of
+	// the trace id, like the Datadog tracer. Otherwise, they're formatted in
+	// hex, like W3C trace context.
+	Datadog bool
+}
+
+var (
+	// DefaultTraceFields logs the ids as trace_id and span_id, in hex.
+	DefaultTraceFields = TraceFields{TraceIDKey: "trace_id", SpanIDKey: "span_id"}
+
+	// DatadogTraceFields logs the ids as dd.trace_id and dd.span_id, which
+	// Datadog uses to link logs and traces.
+	DatadogTraceFields = TraceFields{TraceIDKey: "dd.trace_id", SpanIDKey: "dd.span_id", Datadog: true}
+)
+
+// TraceCorrelation are the trace fields added to messages logged with a
+// context, by Info, Debug, Warn, Error and the slog.Handler returned by
+// NewSlogHandler. Set it to the zero value to disable them. It should be set
+// before logging, when the program starts.
+var TraceCorrelation = DefaultTraceFields
+
+// Pairs returns the key value pairs of the ids of the active span in ctx, see
+// tracecontext.Active. When there's none, the span recorded with RecordSpan is
+// used.
+func (f TraceFields) Pairs(ctx context.Context) []interface{} {
+	if f.TraceIDKey == "" && f.SpanIDKey == "" {
+		return nil
+	}
+
+	traceID, spanID, ok := tracecontext.Active(ctx)
+	if !ok {
+		rec, _ := ctx.Value(spanRecorderKey).(*spanRecorder)
+		if rec == nil {
+			return nil
+		}
+		ids := rec.ids.Load()
+		if ids == nil {
+			return nil
+		}
+		traceID, spanID = ids.traceID, ids.spanID
+	}
+
+	pairs := make([]interface{}, 0, 4)
+	if f.TraceIDKey != "" {
+		v := traceID.String()
+		if f.Datadog {
+			v = strconv.FormatUint(binary.BigEndian.Uint64(traceID[8:]), 10)
+		}
+		pairs = append(pairs, f.TraceIDKey, v)
+	}
+	if f.SpanIDKey != "" {
+		v := spanID.String()
+		if f.Datadog {
+			v = strconv.FormatUint(binary.BigEndian.Uint64(spanID[:]), 10)
+		}
+		pairs = append(pairs, f.SpanIDKey, v)
+	}
+	return pairs
+}
+
+// spanRecorder holds the ids of the span recorded by RecordSpan.
+type spanRecorder struct {
+	ids atomic.Pointer[spanIDs]
+}
+
+type spanIDs struct {
+	traceID tracecontext.TraceID
+	spanID  tracecontext.SpanID
+}
+
+// WithSpanRecorder returns a copy of ctx in which RecordSpan can record the
+// span started by an inner handler, so that messages logged with ctx once it
+// returns, like the request log line, are correlated with it.
+func WithSpanRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, spanRecorderKey, &spanRecorder{})
+}
+
+// RecordSpan records the active span in ctx, see tracecontext.Active, into the
+// recorder added with WithSpanRecorder, if any.
+func RecordSpan(ctx context.Context) {
+	rec, _ := ctx.Value(spanRecorderKey).(*spanRecorder)
+	if rec == nil {
+		return
+	}
+	if traceID, spanID, ok := tracecontext.Active(ctx); ok {
+		rec.ids.Store(&spanIDs{traceID: traceID, spanID: spanID})
+	}
+}
+
+// withTracePairs prepends the TraceCorrelation pairs of ctx to pairs.
+func withTracePairs(ctx context.Context, pairs []interface{}) []interface{} {
+	tp := TraceCorrelation.Pairs(ctx)
+	if len(tp) == 0 {
+		return pairs
+	}
+	return append(tp, pairs...)
+}
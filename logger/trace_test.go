@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+type fakeSpan struct {
+	opentracing.Span
+}
+
+func (s *fakeSpan) Context() opentracing.SpanContext { return fakeSpanContext{} }
+
+type fakeSpanContext struct{}
+
+func (fakeSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+func (fakeSpanContext) TraceID() uint64                           { return 7 }
+func (fakeSpanContext) SpanID() uint64                            { return 42 }
+
+func TestTraceCorrelation(t *testing.T) {
+	defer func(f TraceFields) { TraceCorrelation = f }(TraceCorrelation)
+
+	span := &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")}
+
+	tests := []struct {
+		fields TraceFields
+		out    string
+	}{
+		{DefaultTraceFields, "status=info message trace_id=00000000000000000000000000000007 span_id=000000000000002a key=value\n"},
+		{DatadogTraceFields, "status=info message dd.trace_id=7 dd.span_id=42 key=value\n"},
+		{TraceFields{TraceIDKey: "trace"}, "status=info message trace=00000000000000000000000000000007 key=value\n"},
+		{TraceFields{}, "status=info message key=value\n"},
+	}
+
+	for _, tt := range tests {
+		TraceCorrelation = tt.fields
+
+		b := new(bytes.Buffer)
+		ctx := WithLogger(context.Background(), New(log.New(b, "", 0), INFO))
+		Info(opentracing.ContextWithSpan(ctx, span), "message", "key", "value")
+
+		if got, want := b.String(), tt.out; got != want {
+			t.Errorf("Trace Logger => %q; want %q", got, want)
+		}
+	}
+}
+
+func TestRecordSpan(t *testing.T) {
+	b := new(bytes.Buffer)
+	ctx := WithSpanRecorder(WithLogger(context.Background(), New(log.New(b, "", 0), INFO)))
+
+	Info(ctx, "before")
+	RecordSpan(opentracing.ContextWithSpan(ctx, &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")}))
+	Info(ctx, "after")
+
+	want := "status=info before \n" +
+		"status=info after trace_id=00000000000000000000000000000007 span_id=000000000000002a\n"
+	if got := b.String(); got != want {
+		t.Errorf("Recorded Span Logger => %q; want %q", got, want)
+	}
+}
+
+func TestTraceCorrelation_Disabled(t *testing.T) {
+	b := new(bytes.Buffer)
+	ctx := WithLogger(context.Background(), New(log.New(b, "", 0), INFO))
+	spanCtx := opentracing.ContextWithSpan(ctx, &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")})
+
+	want := testing.AllocsPerRun(100, func() {
+		Debug(ctx, "message", "key", "value")
+	})
+	got := testing.AllocsPerRun(100, func() {
+		Debug(spanCtx, "message", "key", "value")
+	})
+	if got != want {
+		t.Fatalf("Debug => %v allocs; want %v, the trace pairs shouldn't be formatted when the level is disabled", got, want)
+	}
+	if got := b.String(); got != "" {
+		t.Fatalf("Debug => %q; want nothing", got)
+	}
+}
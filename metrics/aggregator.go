@@ -0,0 +1,411 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AggregatorOpts configures an AggregatingMetricsReporter.
+type AggregatorOpts struct {
+	// Interval is how often metrics are flushed. Zero means 10 seconds.
+	// A negative interval disables the periodic flush, leaving it to
+	// Flush and Close.
+	Interval time.Duration
+
+	// MaxSeries bounds the number of series, one per metric kind, name and
+	// tag set, kept between flushes. Calls with new series beyond it are
+	// dropped. Zero means 10000.
+	MaxSeries int
+
+	// MaxSamples bounds the number of Histogram, Distribution and
+	// TimeInMilliseconds samples, and of distinct Set values, that each
+	// series keeps between flushes. Zero means 1000.
+	MaxSamples int
+}
+
+// AggregatingMetricsReporter is a MetricsReporter that aggregates metrics in
+// memory, and flushes them to another MetricsReporter periodically.
+//
+// Counts are summed, gauges keep their last value, and Set values are
+// deduplicated. Histogram, Distribution and TimeInMilliseconds samples are
+// buffered and flushed one by one, since the underlying reporter computes their
+// percentiles. Sample rates are ignored: every call is aggregated, so the
+// totals are exact, and everything is flushed with a rate of 1. Tag sets are
+// interned, so that repeated calls with equal tags don't allocate.
+type AggregatingMetricsReporter struct {
+	reporter MetricsReporter
+	opts     AggregatorOpts
+
+	mu      sync.Mutex
+	series  map[seriesKey]*aggSeries
+	tagSets map[uint64][]*tagSet
+	dropped uint64
+	flushed uint64
+
+	// flushMu serializes flushes, so that metrics are sent in order.
+	flushMu sync.Mutex
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewAggregatingMetricsReporter returns an AggregatingMetricsReporter that
+// flushes to r.
+//
+// Usage:
+//
+//	r, _ := metrics.NewDataDogMetricsReporter("statsd:2026")
+//	metrics.Reporter = metrics.NewAggregatingMetricsReporter(r, metrics.AggregatorOpts{})
+//	defer metrics.Close()
+func NewAggregatingMetricsReporter(r MetricsReporter, opts AggregatorOpts) *AggregatingMetricsReporter {
+	if opts.Interval == 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.MaxSeries == 0 {
+		opts.MaxSeries = 10000
+	}
+	if opts.MaxSamples == 0 {
+		opts.MaxSamples = 1000
+	}
+
+	a := &AggregatingMetricsReporter{
+		reporter: r,
+		opts:     opts,
+		series:   make(map[seriesKey]*aggSeries),
+		tagSets:  make(map[uint64][]*tagSet),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if opts.Interval > 0 {
+		go a.loop()
+	} else {
+		close(a.stopped)
+	}
+	return a
+}
+
+// Kinds of aggregated series.
+const (
+	aggCount = iota
+	aggGauge
+	aggHistogram
+	aggDistribution
+	aggSet
+	aggTiming
+)
+
+type seriesKey struct {
+	kind int
+	name string
+	tags *tagSet
+}
+
+type aggSeries struct {
+	updated bool
+
+	// count
+	count int64
+
+	// gauge
+	value float64
+
+	// histogram, distribution and timing
+	samples []float64
+
+	// set
+	values map[string]struct{}
+}
+
+// tagSet is an interned, immutable, tag set.
+type tagSet struct {
+	tags map[string]string
+}
+
+func (a *AggregatingMetricsReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, err := a.get(aggCount, name, tags)
+	if err != nil {
+		return err
+	}
+	s.count += value
+	return nil
+}
+
+func (a *AggregatingMetricsReporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, err := a.get(aggGauge, name, tags)
+	if err != nil {
+		return err
+	}
+	s.value = value
+	return nil
+}
+
+func (a *AggregatingMetricsReporter) Histogram(name string, value float64, tags map[string]string, rate float64) error {
+	return a.sample(aggHistogram, name, value, tags)
+}
+
+func (a *AggregatingMetricsReporter) Distribution(name string, value float64, tags map[string]string, rate float64) error {
+	return a.sample(aggDistribution, name, value, tags)
+}
+
+func (a *AggregatingMetricsReporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
+	return a.sample(aggTiming, name, value, tags)
+}
+
+func (a *AggregatingMetricsReporter) Set(name string, value string, tags map[string]string, rate float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, err := a.get(aggSet, name, tags)
+	if err != nil {
+		return err
+	}
+	if s.values == nil {
+		s.values = make(map[string]struct{})
+	}
+	if _, ok := s.values[value]; !ok {
+		if len(s.values) >= a.opts.MaxSamples {
+			a.dropped++
+			return nil
+		}
+		s.values[value] = struct{}{}
+	}
+	return nil
+}
+
+func (a *AggregatingMetricsReporter) sample(kind int, name string, value float64, tags map[string]string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, err := a.get(kind, name, tags)
+	if err != nil {
+		return err
+	}
+	if len(s.samples) >= a.opts.MaxSamples {
+		a.dropped++
+		return nil
+	}
+	s.samples = append(s.samples, value)
+	return nil
+}
+
+// get returns the series for kind, name and tags, marked as updated. a.mu must
+// be held.
+func (a *AggregatingMetricsReporter) get(kind int, name string, tags map[string]string) (*aggSeries, error) {
+	key := seriesKey{kind: kind, name: name, tags: a.intern(tags)}
+
+	s, ok := a.series[key]
+	if !ok {
+		if len(a.series) >= a.opts.MaxSeries {
+			a.dropped++
+			return nil, fmt.Errorf("metrics: too many series, dropping %s", name)
+		}
+		s = &aggSeries{}
+		a.series[key] = s
+	}
+	s.updated = true
+	return s, nil
+}
+
+// intern returns the interned tag set equal to tags, creating it if needed.
+// a.mu must be held.
+func (a *AggregatingMetricsReporter) intern(tags map[string]string) *tagSet {
+	h := hashTags(tags)
+	for _, ts := range a.tagSets[h] {
+		if equalTags(ts.tags, tags) {
+			return ts
+		}
+	}
+
+	ts := &tagSet{tags: make(map[string]string, len(tags))}
+	for k, v := range tags {
+		ts.tags[k] = v
+	}
+	a.tagSets[h] = append(a.tagSets[h], ts)
+	return ts
+}
+
+// Flush sends the metrics aggregated since the last flush to the underlying
+// reporter. It returns the first error the reporter returned, if any.
+func (a *AggregatingMetricsReporter) Flush() error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	// Collect the updated series, and reset them, while holding the lock.
+	// Series that weren't updated since the last flush are forgotten, along
+	// with the tag sets that aren't used anymore.
+	type flushed struct {
+		seriesKey
+		aggSeries
+	}
+	var batch []flushed
+
+	a.mu.Lock()
+	for key, s := range a.series {
+		if !s.updated {
+			delete(a.series, key)
+			continue
+		}
+		batch = append(batch, flushed{key, *s})
+		*s = aggSeries{}
+	}
+	tagSets := make(map[uint64][]*tagSet)
+	for key := range a.series {
+		h := hashTags(key.tags.tags)
+		if !containsTagSet(tagSets[h], key.tags) {
+			tagSets[h] = append(tagSets[h], key.tags)
+		}
+	}
+	a.tagSets = tagSets
+	a.mu.Unlock()
+
+	var (
+		firstErr error
+		n        uint64
+	)
+	report := func(err error) {
+		n++
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, f := range batch {
+		tags := f.tags.tags
+		switch f.kind {
+		case aggCount:
+			report(a.reporter.Count(f.name, f.count, tags, 1.0))
+		case aggGauge:
+			report(a.reporter.Gauge(f.name, f.value, tags, 1.0))
+		case aggHistogram:
+			for _, v := range f.samples {
+				report(a.reporter.Histogram(f.name, v, tags, 1.0))
+			}
+		case aggDistribution:
+			for _, v := range f.samples {
+				report(a.reporter.Distribution(f.name, v, tags, 1.0))
+			}
+		case aggTiming:
+			for _, v := range f.samples {
+				report(a.reporter.TimeInMilliseconds(f.name, v, tags, 1.0))
+			}
+		case aggSet:
+			for v := range f.values {
+				report(a.reporter.Set(f.name, v, tags, 1.0))
+			}
+		}
+	}
+
+	a.mu.Lock()
+	a.flushed += n
+	a.mu.Unlock()
+
+	return firstErr
+}
+
+// Dropped returns the number of calls that were dropped because of the
+// MaxSeries and MaxSamples limits.
+func (a *AggregatingMetricsReporter) Dropped() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Flushed returns the number of calls made to the underlying reporter.
+func (a *AggregatingMetricsReporter) Flushed() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flushed
+}
+
+// Close stops the periodic flush, flushes the remaining metrics and closes the
+// underlying reporter.
+func (a *AggregatingMetricsReporter) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.done)
+		<-a.stopped
+
+		err = a.Flush()
+		if cerr := a.reporter.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+func (a *AggregatingMetricsReporter) loop() {
+	defer close(a.stopped)
+
+	t := time.NewTicker(a.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			a.Flush()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// hashTags returns a hash of tags that doesn't depend on the iteration order,
+// without allocating.
+func hashTags(tags map[string]string) uint64 {
+	var h uint64
+	for k, v := range tags {
+		// Pairs are combined by addition, which is commutative.
+		h += hashPair(k, v)
+	}
+	return h
+}
+
+// hashPair returns the FNV-1a hash of k, a separator, and v.
+func hashPair(k, v string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(k); i++ {
+		h ^= uint64(k[i])
+		h *= prime
+	}
+	h ^= 0xff
+	h *= prime
+	for i := 0; i < len(v); i++ {
+		h ^= uint64(v[i])
+		h *= prime
+	}
+	return h
+}
+
+func equalTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTagSet(sets []*tagSet, ts *tagSet) bool {
+	for _, s := range sets {
+		if s == ts {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package metrics_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/metrics/metricstest"
+)
+
+func TestAggregatingMetricsReporter(t *testing.T) {
+	r := metricstest.NewReporter()
+	a := metrics.NewAggregatingMetricsReporter(r, metrics.AggregatorOpts{Interval: -1})
+
+	for i := 0; i < 10; i++ {
+		a.Count("requests", 1, map[string]string{"status": "200", "route": "/"}, 1.0)
+	}
+	a.Count("requests", 1, map[string]string{"route": "/", "status": "500"}, 0.5)
+	a.Gauge("queue.depth", 1, nil, 1.0)
+	a.Gauge("queue.depth", 2, nil, 1.0)
+	a.TimeInMilliseconds("request.time", 5, nil, 0.5)
+	a.TimeInMilliseconds("request.time", 10, nil, 1.0)
+	a.Set("users", "a", nil, 1.0)
+	a.Set("users", "a", nil, 1.0)
+
+	if got := len(r.Calls()); got != 0 {
+		t.Fatalf("got %d calls before flushing; expected none", got)
+	}
+
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	metricstest.AssertCounter(t, r, "requests", map[string]string{"status": "200"}, 10)
+	metricstest.AssertCounter(t, r, "requests", map[string]string{"status": "500"}, 1)
+	metricstest.AssertGauge(t, r, "queue.depth", nil, 2)
+	if got, want := r.Timings("request.time", nil), []float64{5, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+	for _, c := range r.Calls() {
+		if c.Rate != 1 {
+			t.Errorf("got %s; expected it to be flushed with a rate of 1", c)
+		}
+	}
+	if got, want := len(r.Find(metricstest.KindSet, "users", nil)), 1; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if got, want := a.Flushed(), uint64(6); got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+
+	// Series that weren't updated aren't flushed again.
+	r.Reset()
+	a.Count("requests", 1, map[string]string{"status": "200", "route": "/"}, 1.0)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	metricstest.AssertCalls(t, r, metricstest.Call{
+		Kind:  metricstest.KindCount,
+		Name:  "requests",
+		Value: 1,
+		Tags:  map[string]string{"status": "200", "route": "/"},
+		Rate:  1,
+	})
+	if !r.Closed() {
+		t.Error("expected the underlying reporter to be closed")
+	}
+}
+
+func TestAggregatingMetricsReporter_Limits(t *testing.T) {
+	r := metricstest.NewReporter()
+	a := metrics.NewAggregatingMetricsReporter(r, metrics.AggregatorOpts{
+		Interval:   -1,
+		MaxSeries:  2,
+		MaxSamples: 2,
+	})
+
+	a.Histogram("size", 1, nil, 1.0)
+	a.Histogram("size", 2, nil, 1.0)
+	a.Histogram("size", 3, nil, 1.0)
+	a.Count("requests", 1, map[string]string{"id": "1"}, 1.0)
+	if err := a.Count("requests", 1, map[string]string{"id": "2"}, 1.0); err == nil {
+		t.Error("expected an error past MaxSeries")
+	}
+
+	if got, want := a.Dropped(), uint64(2); got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+
+	a.Flush()
+	if got, want := r.Histograms("size", nil), []float64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+}
+
+var benchmarkTags = map[string]string{
+	"route":  "GET /users/{id}",
+	"status": "200",
+	"tier":   "gold",
+}
+
+func BenchmarkDataDogMetricsReporter_Count(b *testing.B) {
+	r, err := metrics.NewDataDogMetricsReporter("127.0.0.1:8125")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer r.Close()
+	defer withReporter(r)()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metrics.Count("requests", 1, benchmarkTags, 1.0)
+	}
+}
+
+func BenchmarkAggregatingMetricsReporter_Count(b *testing.B) {
+	r, err := metrics.NewDataDogMetricsReporter("127.0.0.1:8125")
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := metrics.NewAggregatingMetricsReporter(r, metrics.AggregatorOpts{})
+	defer a.Close()
+	defer withReporter(a)()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metrics.Count("requests", 1, benchmarkTags, 1.0)
+	}
+}
+
+func BenchmarkAggregatingMetricsReporter_CountParallel(b *testing.B) {
+	a := metrics.NewAggregatingMetricsReporter(&metrics.NoopMetricsReporter{}, metrics.AggregatorOpts{})
+	defer a.Close()
+	defer withReporter(a)()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			metrics.Count("requests", 1, benchmarkTags, 1.0)
+		}
+	})
+}
+
+func withReporter(r metrics.MetricsReporter) func() {
+	orig := metrics.Reporter
+	metrics.Reporter = r
+	return func() { metrics.Reporter = orig }
+}
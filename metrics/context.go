@@ -0,0 +1,90 @@
+package metrics
+
+import "context"
+
+// WithTags returns a copy of ctx carrying tags, which the Ctx variants of the
+// reporting functions add to each metric. They're merged with the tags already
+// in ctx, replacing the ones with the same keys.
+//
+// Usage:
+//
+//	ctx = metrics.WithTags(ctx, map[string]string{"route": "GET /users"})
+//	...
+//	metrics.CountCtx(ctx, "users.listed", 1, nil, 1.0)
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	parent := TagsFromContext(ctx)
+	merged := make(map[string]string, len(parent)+len(tags))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, tagsKey, merged)
+}
+
+// TagsFromContext returns the tags added to ctx with WithTags. The returned map
+// must not be modified.
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey).(map[string]string)
+	return tags
+}
+
+func CountCtx(ctx context.Context, name string, value int64, tags map[string]string, rate float64) error {
+	return Count(name, value, withContextTags(ctx, tags), rate)
+}
+
+func GaugeCtx(ctx context.Context, name string, value float64, tags map[string]string, rate float64) error {
+	return Gauge(name, value, withContextTags(ctx, tags), rate)
+}
+
+func HistogramCtx(ctx context.Context, name string, value float64, tags map[string]string, rate float64) error {
+	return Histogram(name, value, withContextTags(ctx, tags), rate)
+}
+
+func DistributionCtx(ctx context.Context, name string, value float64, tags map[string]string, rate float64) error {
+	return Distribution(name, value, withContextTags(ctx, tags), rate)
+}
+
+func SetCtx(ctx context.Context, name string, value string, tags map[string]string, rate float64) error {
+	return Set(name, value, withContextTags(ctx, tags), rate)
+}
+
+func TimeInMillisecondsCtx(ctx context.Context, name string, value float64, tags map[string]string, rate float64) error {
+	return TimeInMilliseconds(name, value, withContextTags(ctx, tags), rate)
+}
+
+// TimeCtx is like Time, with the tags in ctx added to the metric.
+//
+// Usage:
+//
+//	t := metrics.TimeCtx(ctx, "foo.bar", map[string]string{"baz":"qux"}, 1.0)
+//	defer t.Done()
+func TimeCtx(ctx context.Context, name string, tags map[string]string, rate float64) *timer {
+	return Time(name, withContextTags(ctx, tags), rate)
+}
+
+// withContextTags merges the tags in ctx with tags, which take precedence.
+// Default tags are added later, by the reporting functions, with the lowest
+// precedence.
+func withContextTags(ctx context.Context, tags map[string]string) map[string]string {
+	ctxTags := TagsFromContext(ctx)
+	if len(ctxTags) == 0 {
+		return tags
+	}
+	result := make(map[string]string, len(ctxTags)+len(tags))
+	for k, v := range ctxTags {
+		result[k] = v
+	}
+	for k, v := range tags {
+		result[k] = v
+	}
+	return result
+}
+
+// key used to store context values from within this package.
+type key int
+
+const (
+	tagsKey key = iota
+)
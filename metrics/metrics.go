@@ -1,6 +1,9 @@
 package metrics
 
-import "os"
+import (
+	"os"
+	"sync"
+)
 
 // Usage:
 //   metrics.SetEmpireDefaultTags()
@@ -17,6 +20,12 @@ func init() {
 	resetDefaultTags()
 }
 
+// MetricsReporter reports metrics to a backend.
+//
+// The tags maps passed to a MetricsReporter are shared with the caller, and
+// with other goroutines reporting the same tags, so they must be treated as
+// read-only. A MetricsReporter that needs to add or change tags, or to keep
+// them after returning, must copy them.
 type MetricsReporter interface {
 	Count(name string, value int64, tags map[string]string, rate float64) error
 	Gauge(name string, value float64, tags map[string]string, rate float64) error
@@ -32,10 +41,21 @@ func SetEmpireDefaultTags() {
 	defaultTags["empire.app.name"] = os.Getenv("EMPIRE_APPNAME")
 	defaultTags["empire.app.process"] = os.Getenv("EMPIRE_PROCESS")
 	defaultTags["empire.app.release"] = os.Getenv("EMPIRE_RELEASE")
+	resetMergedTags()
+}
+
+// SetDefaultTags adds tags to each metric, replacing existing default tags
+// with the same keys.
+func SetDefaultTags(tags map[string]string) {
+	for k, v := range tags {
+		defaultTags[k] = v
+	}
+	resetMergedTags()
 }
 
 func resetDefaultTags() {
 	defaultTags = make(map[string]string, 1)
+	resetMergedTags()
 }
 
 func resetReporter() {
@@ -97,16 +117,65 @@ func ResponseTime() *timer {
 	return t
 }
 
+// withDefaultTags returns tags merged with the default tags, which have a lower
+// precedence. Merged tag sets are interned, up to maxMergedTags, so that
+// reporting with equal tags doesn't allocate. The result is shared, see
+// MetricsReporter.
 func withDefaultTags(tags map[string]string) map[string]string {
-	if tags == nil && defaultTags == nil {
-		return nil
+	if len(defaultTags) == 0 && tags != nil {
+		return tags
 	}
-	result := make(map[string]string, len(tags)+len(defaultTags))
+
+	h := hashTags(tags)
+	mergedTags.RLock()
+	for _, m := range mergedTags.sets[h] {
+		if equalTags(m.tags, tags) {
+			mergedTags.RUnlock()
+			return m.merged
+		}
+	}
+	mergedTags.RUnlock()
+
+	merged := make(map[string]string, len(tags)+len(defaultTags))
 	for k, v := range defaultTags {
-		result[k] = v
+		merged[k] = v
 	}
 	for k, v := range tags {
-		result[k] = v
+		merged[k] = v
 	}
-	return result
+
+	mergedTags.Lock()
+	defer mergedTags.Unlock()
+	if mergedTags.n < maxMergedTags {
+		c := make(map[string]string, len(tags))
+		for k, v := range tags {
+			c[k] = v
+		}
+		mergedTags.sets[h] = append(mergedTags.sets[h], mergedTagSet{tags: c, merged: merged})
+		mergedTags.n++
+	}
+	return merged
+}
+
+// maxMergedTags bounds the number of tag sets interned by withDefaultTags.
+const maxMergedTags = 10000
+
+type mergedTagSet struct {
+	tags, merged map[string]string
+}
+
+// mergedTags are the tag sets interned by withDefaultTags, by hashTags.
+var mergedTags struct {
+	sync.RWMutex
+	sets map[uint64][]mergedTagSet
+	n    int
+}
+
+// resetMergedTags forgets the interned tag sets, when the default tags
+// change.
+func resetMergedTags() {
+	mergedTags.Lock()
+	defer mergedTags.Unlock()
+	mergedTags.sets = make(map[uint64][]mergedTagSet)
+	mergedTags.n = 0
 }
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"testing"
@@ -77,3 +78,48 @@ func TestTime(t *testing.T) {
 		t.Errorf("got tags:\n\t%#v\nwanted these:\n\t%#v", gotTags, wantTags)
 	}
 }
+
+func TestContextTags(t *testing.T) {
+	r := newFakeMetricsReporter()
+	Reporter = r
+	defer resetReporter()
+
+	SetDefaultTags(map[string]string{"env": "test", "tier": "default"})
+	defer resetDefaultTags()
+
+	ctx := WithTags(context.Background(), map[string]string{"tier": "free", "route": "GET /"})
+	ctx = WithTags(ctx, map[string]string{"tier": "gold"})
+
+	must(t, CountCtx(ctx, "testcount", 1, map[string]string{"route": "GET /users"}, 1.0))
+	assertDeepEqual(t, "default, context and call site tags", r.LastCountMetric, &intMetric{
+		metric{"testcount", map[string]string{"env": "test", "tier": "gold", "route": "GET /users"}, 1.0},
+		1,
+	})
+
+	tm := TimeCtx(ctx, "mytiming", nil, 1.0)
+	tm.SetTags(map[string]string{"status": "200"})
+	tm.Done()
+	assertDeepEqual(t, "timer tags", r.LastTimeInMillisecondsMetric.Tags,
+		map[string]string{"env": "test", "tier": "gold", "route": "GET /", "status": "200"})
+
+	if got, want := TagsFromContext(context.Background()), map[string]string(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+}
+
+func BenchmarkCount_DefaultTags(b *testing.B) {
+	Reporter = NewAggregatingMetricsReporter(&NoopMetricsReporter{}, AggregatorOpts{})
+	defer resetReporter()
+	defer Reporter.Close()
+
+	SetDefaultTags(map[string]string{"env": "test", "tier": "default"})
+	defer resetDefaultTags()
+
+	tags := map[string]string{"route": "GET /users/{id}", "status": "200"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Count("requests", 1, tags, 1.0)
+	}
+}
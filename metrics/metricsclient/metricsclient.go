@@ -0,0 +1,312 @@
+// Package metricsclient reports metrics about outgoing HTTP requests, for the
+// client and httpx clients.
+//
+// Usage:
+//
+//	ctx, trace := metricsclient.WithTrace(ctx)
+//	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+//	metricsclient.Report(ctx, metricsclient.Request{
+//		Service:   "users",
+//		Operation: "GetUser",
+//		Response:  resp,
+//		Err:       err,
+//		Duration:  time.Since(trace.Timings().Start),
+//		Trace:     trace,
+//	})
+package metricsclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/remind101/pkg/metrics"
+)
+
+// Metric names reported by Report.
+const (
+	MetricRequestTime = "http.client.request.time"
+	MetricRetries     = "http.client.retries"
+	MetricDNSTime     = "http.client.dns.time"
+	MetricConnectTime = "http.client.connect.time"
+	MetricTLSTime     = "http.client.tls.time"
+	MetricTTFB        = "http.client.ttfb"
+)
+
+// Values of the error tag.
+const (
+	ErrorNone       = "none"
+	ErrorTimeout    = "timeout"
+	ErrorCanceled   = "canceled"
+	ErrorDNS        = "dns"
+	ErrorConnection = "connection"
+	ErrorOther      = "error"
+)
+
+// Timings is the breakdown of a request, recorded by a Trace. When a request
+// is retried, the durations are those of the last attempt.
+type Timings struct {
+	// Start is when the Trace started.
+	Start time.Time
+
+	// Attempts is the number of connections obtained, one per attempt,
+	// redirect or new dial. It's not the number of retries, see Retries.
+	Attempts int
+
+	// Retries is the number of times the request was retried, counted by
+	// the retrying transport with Trace.Retried.
+	Retries int
+
+	// Durations of the DNS lookup, the connection, the TLS handshake and
+	// the time from writing the request to the first response byte. They're
+	// zero when the step didn't happen, like when a connection is reused.
+	DNS, Connect, TLS, TimeToFirstByte time.Duration
+
+	// Reused is whether the connection of the last attempt was reused.
+	Reused bool
+}
+
+// Tags returns the timings as span tags, with durations in milliseconds.
+func (t Timings) Tags() map[string]interface{} {
+	return map[string]interface{}{
+		"http.attempts":    t.Attempts,
+		"http.retries":     t.Retries,
+		"http.dns_ms":      milliseconds(t.DNS),
+		"http.connect_ms":  milliseconds(t.Connect),
+		"http.tls_ms":      milliseconds(t.TLS),
+		"http.ttfb_ms":     milliseconds(t.TimeToFirstByte),
+		"http.conn_reused": t.Reused,
+	}
+}
+
+// Trace records the Timings of requests with httptrace.
+type Trace struct {
+	mu      sync.Mutex
+	timings Timings
+
+	dnsStart, connectStart, tlsStart, wroteAt time.Time
+}
+
+type key int
+
+const (
+	traceKey key = iota
+	operationKey
+)
+
+// WithTrace returns a copy of ctx that traces the requests sent with it. If
+// ctx already has a Trace, it's returned.
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	if t := TraceFromContext(ctx); t != nil {
+		return ctx, t
+	}
+
+	t := &Trace{timings: Timings{Start: time.Now()}}
+	ctx = httptrace.WithClientTrace(ctx, t.clientTrace())
+	return context.WithValue(ctx, traceKey, t), t
+}
+
+// TraceFromContext returns the Trace of a context returned by WithTrace, or
+// nil.
+func TraceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey).(*Trace)
+	return t
+}
+
+// WithOperation returns a copy of ctx that names the operation of the requests
+// sent with it, for the operation tag.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey, operation)
+}
+
+// OperationFromContext returns the operation set with WithOperation, or "".
+func OperationFromContext(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey).(string)
+	return operation
+}
+
+func (t *Trace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings = Timings{
+				Start:    t.timings.Start,
+				Attempts: t.timings.Attempts + 1,
+				Retries:  t.timings.Retries,
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.Reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.Connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLS = time.Since(t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wroteAt = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.wroteAt.IsZero() {
+				t.timings.TimeToFirstByte = time.Since(t.wroteAt)
+			}
+		},
+	}
+}
+
+// Retried records that the request is sent again, for Timings.Retries and
+// the MetricRetries count. It's called by the transports that retry requests,
+// like httpx.RetryTransport.
+func (t *Trace) Retried() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timings.Retries++
+}
+
+// Timings returns the Timings recorded so far.
+func (t *Trace) Timings() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings
+}
+
+// Request describes a finished outgoing request, for Report.
+type Request struct {
+	// Service is the name of the service called.
+	Service string
+
+	// Operation names the call, like GetUser. It defaults to the operation
+	// in the context, see WithOperation, then to the method of the request.
+	Operation string
+
+	// Method of the request, used when Operation isn't set.
+	Method string
+
+	// Response is the response, if any.
+	Response *http.Response
+
+	// Err is the error returned when sending the request, if any.
+	Err error
+
+	// Duration of the request, retries included.
+	Duration time.Duration
+
+	// Trace of the request, if any.
+	Trace *Trace
+}
+
+// Report reports metrics about an outgoing request, tagged with service,
+// operation, status, status_class, error, one of the Error values, and the
+// tags in ctx, see metrics.WithTags.
+func Report(ctx context.Context, r Request) {
+	operation := r.Operation
+	if operation == "" {
+		operation = OperationFromContext(ctx)
+	}
+	if operation == "" {
+		operation = r.Method
+	}
+	status := 0
+	if r.Response != nil {
+		status = r.Response.StatusCode
+	}
+
+	tags := map[string]string{
+		"service":      r.Service,
+		"operation":    operation,
+		"status":       strconv.Itoa(status),
+		"status_class": strconv.Itoa(status/100) + "xx",
+		"error":        ErrorClass(r.Err),
+	}
+
+	metrics.TimeInMillisecondsCtx(ctx, MetricRequestTime, milliseconds(r.Duration), tags, 1.0)
+
+	if r.Trace == nil {
+		return
+	}
+	t := r.Trace.Timings()
+	if t.Retries > 0 {
+		metrics.CountCtx(ctx, MetricRetries, int64(t.Retries), tags, 1.0)
+	}
+
+	tags["conn_reused"] = strconv.FormatBool(t.Reused)
+	for _, m := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{MetricDNSTime, t.DNS},
+		{MetricConnectTime, t.Connect},
+		{MetricTLSTime, t.TLS},
+		{MetricTTFB, t.TimeToFirstByte},
+	} {
+		if m.d > 0 {
+			metrics.TimeInMillisecondsCtx(ctx, m.name, milliseconds(m.d), tags, 1.0)
+		}
+	}
+}
+
+// ErrorClass classifies an error returned when sending a request.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ErrorNone
+	}
+
+	var (
+		netErr net.Error
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &opErr):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
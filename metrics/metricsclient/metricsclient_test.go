@@ -0,0 +1,127 @@
+package metricsclient_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/metrics/metricsclient"
+	"github.com/remind101/pkg/metrics/metricstest"
+)
+
+func TestReport(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	send := func(ctx context.Context) metricsclient.Timings {
+		ctx, trace := metricsclient.WithTrace(ctx)
+		req, _ := http.NewRequest("POST", s.URL, nil)
+		resp, err := s.Client().Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		metricsclient.Report(ctx, metricsclient.Request{
+			Service:  "users",
+			Method:   req.Method,
+			Response: resp,
+			Duration: time.Since(trace.Timings().Start),
+			Trace:    trace,
+		})
+		return trace.Timings()
+	}
+
+	ctx := metrics.WithTags(context.Background(), map[string]string{"tier": "gold"})
+	first := send(metricsclient.WithOperation(ctx, "CreateUser"))
+	second := send(ctx)
+
+	if got, want := first.Attempts, 1; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if first.Reused || first.Connect == 0 || first.TimeToFirstByte == 0 {
+		t.Errorf("expected a new connection to be timed, got %+v", first)
+	}
+	if !second.Reused || second.Connect != 0 {
+		t.Errorf("expected the connection to be reused, got %+v", second)
+	}
+
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricRequestTime, map[string]string{
+		"service":      "users",
+		"operation":    "CreateUser",
+		"status":       "201",
+		"status_class": "2xx",
+		"error":        metricsclient.ErrorNone,
+		"tier":         "gold",
+	})
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricRequestTime, map[string]string{
+		"operation": "POST",
+	})
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricConnectTime, map[string]string{
+		"conn_reused": "false",
+	})
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricsclient.MetricTTFB, map[string]string{
+		"conn_reused": "true",
+	})
+	metricstest.AssertNotReported(t, reporter, metricstest.KindCount, metricsclient.MetricRetries, nil)
+}
+
+func TestReport_Retries(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	ctx, trace := metricsclient.WithTrace(context.Background())
+	for i := 0; i < 3; i++ {
+		// Connections, like redirects, aren't retries.
+		httptrace.ContextClientTrace(ctx).GetConn("users:80")
+	}
+	trace.Retried()
+	trace.Retried()
+	if got, want := trace.Timings().Attempts, 3; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	metricsclient.Report(ctx, metricsclient.Request{
+		Service: "users",
+		Method:  "GET",
+		Err:     errors.New("boom"),
+		Trace:   trace,
+	})
+
+	metricstest.AssertCounter(t, reporter, metricsclient.MetricRetries, map[string]string{
+		"service": "users",
+		"status":  "0",
+		"error":   metricsclient.ErrorOther,
+	}, 2)
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{nil, metricsclient.ErrorNone},
+		{errors.New("boom"), metricsclient.ErrorOther},
+		{&url.Error{Op: "Get", Err: context.Canceled}, metricsclient.ErrorCanceled},
+		{&url.Error{Op: "Get", Err: context.DeadlineExceeded}, metricsclient.ErrorTimeout},
+		{&url.Error{Op: "Get", Err: &net.DNSError{Err: "no such host", Name: "users"}}, metricsclient.ErrorDNS},
+		{&url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, metricsclient.ErrorConnection},
+	}
+
+	for _, tt := range tests {
+		if got, want := metricsclient.ErrorClass(tt.err), tt.class; got != want {
+			t.Errorf("%v: got %s; expected %s", tt.err, got, want)
+		}
+	}
+}
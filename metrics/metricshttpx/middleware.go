@@ -1,7 +1,6 @@
 package metricshttpx
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -23,6 +22,10 @@ import (
 //   }).Methods("GET")
 //   s := NewResponseTimeReporter(r, r)
 //
+// The route is recorded by the router that handles the request, see
+// httpx.RecordedRoute. When none was recorded, like when handler doesn't reach
+// a Router, the request is matched with router. See ServerMetrics for more
+// metrics.
 func NewResponseTimeReporter(handler httpx.Handler, router *httpx.Router) *responseTimeReporter {
 	if router == nil {
 		panic("NewResponseTimeReporter: router is requred")
@@ -39,10 +42,14 @@ func (h *responseTimeReporter) ServeHTTPContext(ctx context.Context, w http.Resp
 	t := metrics.ResponseTime()
 	defer t.Done()
 
+	ctx = httpx.WithRouteRecorder(ctx)
 	rw := middleware.NewResponseWriter(w) // exposes status code
 	err := h.handler.ServeHTTPContext(ctx, rw, r)
 
-	route := fmt.Sprintf("%s %s", r.Method, templatePath(h.router, r))
+	route := routeName(ctx, r)
+	if httpx.RecordedRoute(ctx) == nil {
+		route = r.Method + " " + templatePath(h.router, r)
+	}
 	status := strconv.Itoa(rw.Status())
 	t.SetTags(map[string]string{
 		"route":  route,
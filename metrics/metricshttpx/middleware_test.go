@@ -8,15 +8,14 @@ import (
 
 	"github.com/remind101/pkg/httpx"
 	"github.com/remind101/pkg/httpx/middleware"
-	"github.com/remind101/pkg/metrics"
 	"github.com/remind101/pkg/metrics/metricshttpx"
+	"github.com/remind101/pkg/metrics/metricstest"
 	"context"
 )
 
 func TestMiddlewareReportsResponseTimeMetrics(t *testing.T) {
-	fakeReporter := &fakeMetricsReporter{}
-	cleanup := withReporter(fakeReporter)
-	defer cleanup()
+	reporter, restore := metricstest.Install()
+	defer restore()
 
 	r := httpx.NewRouter()
 	r.HandleFunc("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -35,45 +34,49 @@ func TestMiddlewareReportsResponseTimeMetrics(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	gotName, wantName := fakeReporter.LatestTimingMetricName, "response.time"
-	if gotName != wantName {
-		t.Errorf("expected response time metric name to be %s, got %s", wantName, gotName)
+	timings := reporter.Find(metricstest.KindTiming, "response.time", nil)
+	if len(timings) != 1 {
+		t.Fatalf("expected one response time metric, got %d", len(timings))
 	}
 
-	gotTime := fakeReporter.LatestTimingMetricValue
-	if gotTime == 0 {
+	if timings[0].Value == 0 {
 		t.Errorf("expected response time to be reported")
 	}
 
-	gotTags := fakeReporter.LatestTimingMetricTags
+	gotTags := timings[0].Tags
 	wantTags := map[string]string{
 		"route":  "GET /",
 		"status": "201",
 	}
 
 	if !reflect.DeepEqual(gotTags, wantTags) {
-		t.Errorf("expected tags:\n\t%v\\ngot tags:%v\n\t", wantTags, gotTags)
+		t.Errorf("expected tags:\n\t%v\ngot tags:%v\n\t", wantTags, gotTags)
 	}
 }
 
-func withReporter(reporter metrics.MetricsReporter) func() {
-	oldReporter := metrics.Reporter
-	metrics.Reporter = reporter
-	return func() {
-		metrics.Reporter = oldReporter
-	}
-}
+func TestMiddlewareMatchesRouteWithoutRecorder(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
 
-type fakeMetricsReporter struct {
-	metrics.NoopMetricsReporter
-	LatestTimingMetricName  string
-	LatestTimingMetricValue float64
-	LatestTimingMetricTags  map[string]string
-}
+	r := httpx.NewRouter()
+	r.Handle("/users/{id}", nil).Methods("GET")
+
+	h := httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+	s := metricshttpx.NewResponseTimeReporter(h, r)
 
-func (r *fakeMetricsReporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
-	r.LatestTimingMetricName = name
-	r.LatestTimingMetricValue = value
-	r.LatestTimingMetricTags = tags
-	return nil
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	if err := s.ServeHTTPContext(context.Background(), httptest.NewRecorder(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	timings := reporter.Find(metricstest.KindTiming, "response.time", nil)
+	if len(timings) != 1 {
+		t.Fatalf("expected one response time metric, got %d", len(timings))
+	}
+	if got, want := timings[0].Tags["route"], "GET /users/{id}"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
 }
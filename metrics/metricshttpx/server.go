@@ -0,0 +1,177 @@
+package metricshttpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	httpxerrors "github.com/remind101/pkg/httpx/errors"
+	"github.com/remind101/pkg/httpx/middleware"
+	"github.com/remind101/pkg/metrics"
+)
+
+// Metric names reported by ServerMetrics.
+const (
+	MetricInFlight     = "http.server.in_flight"
+	MetricResponseTime = "response.time"
+	MetricRequestSize  = "http.server.request.size"
+	MetricResponseSize = "http.server.response.size"
+	MetricSlow         = "http.server.slow"
+)
+
+// Values of the error tag.
+const (
+	ErrorNone     = "none"
+	ErrorTimeout  = "timeout"
+	ErrorPanic    = "panic"
+	ErrorCanceled = "canceled"
+	ErrorOther    = "error"
+)
+
+// Opts configures ServerMetrics.
+type Opts struct {
+	// SlowThreshold is the duration above which requests are counted as
+	// slow. Zero disables it, except for the routes in SlowThresholds.
+	SlowThreshold time.Duration
+
+	// SlowThresholds overrides SlowThreshold per route, keyed like the
+	// route tag, for instance "GET /users/{id}".
+	SlowThresholds map[string]time.Duration
+}
+
+// ServerMetrics reports metrics about the requests it serves:
+//
+//	http.server.in_flight      gauge of the requests being served
+//	response.time              timing of requests
+//	http.server.request.size   histogram of the request body bytes read
+//	http.server.response.size  histogram of the response body bytes written
+//	http.server.slow           count of requests slower than their threshold
+//
+// All but http.server.in_flight are tagged with route, status, status_class,
+// like 2xx, and error, one of the Error values, along with the tags in the
+// request context, see metrics.WithTags. When the handler returns an error,
+// or panics, without writing a response, the status is the one the error
+// handler responds with, see httpx.ErrorStatusCode, or 500 for panics.
+//
+// The route comes from the httpx.Router that handles the request, see
+// httpx.RecordedRoute. Requests that no route matched are tagged with the
+// "unknown" route.
+type ServerMetrics struct {
+	handler  httpx.Handler
+	opts     Opts
+	inFlight int64
+}
+
+// NewServerMetrics returns a ServerMetrics that reports metrics about the
+// requests served by h.
+//
+// Usage:
+//
+//	r := httpx.NewRouter()
+//	...
+//	h := metricshttpx.NewServerMetrics(r, metricshttpx.Opts{
+//		SlowThreshold: time.Second,
+//	})
+func NewServerMetrics(h httpx.Handler, opts Opts) *ServerMetrics {
+	return &ServerMetrics{handler: h, opts: opts}
+}
+
+func (h *ServerMetrics) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+	metrics.Gauge(MetricInFlight, float64(atomic.AddInt64(&h.inFlight, 1)), nil, 1.0)
+
+	ctx = httpx.WithRouteRecorder(ctx)
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	rw := middleware.NewResponseWriter(w)
+	start := time.Now()
+
+	panicked := true
+	defer func() {
+		metrics.Gauge(MetricInFlight, float64(atomic.AddInt64(&h.inFlight, -1)), nil, 1.0)
+
+		errType, status := ErrorPanic, rw.Status()
+		if !panicked {
+			errType = errorType(ctx, r, err)
+		}
+		if !rw.Written() {
+			// The response is left to the error handling
+			// middleware, which derives the status the same way.
+			switch {
+			case panicked:
+				status = http.StatusInternalServerError
+			case err != nil:
+				status = httpx.ErrorStatusCode(err)
+			}
+		}
+		h.report(ctx, r, rw, status, body.n, time.Since(start), errType)
+	}()
+
+	err = h.handler.ServeHTTPContext(ctx, rw, r)
+	panicked = false
+	return err
+}
+
+func (h *ServerMetrics) report(ctx context.Context, r *http.Request, rw middleware.ResponseWriter, status int, bytesIn int64, d time.Duration, errType string) {
+	route := routeName(ctx, r)
+	tags := map[string]string{
+		"route":        route,
+		"status":       strconv.Itoa(status),
+		"status_class": strconv.Itoa(status/100) + "xx",
+		"error":        errType,
+	}
+
+	metrics.TimeInMillisecondsCtx(ctx, MetricResponseTime, float64(d)/float64(time.Millisecond), tags, 1.0)
+	metrics.HistogramCtx(ctx, MetricRequestSize, float64(bytesIn), tags, 1.0)
+	metrics.HistogramCtx(ctx, MetricResponseSize, float64(rw.Size()), tags, 1.0)
+
+	threshold, ok := h.opts.SlowThresholds[route]
+	if !ok {
+		threshold = h.opts.SlowThreshold
+	}
+	if threshold > 0 && d > threshold {
+		metrics.CountCtx(ctx, MetricSlow, 1, tags, 1.0)
+	}
+}
+
+// routeName returns the method and path template of the route that handled r.
+func routeName(ctx context.Context, r *http.Request) string {
+	route := httpx.RecordedRoute(ctx)
+	if route == nil {
+		return r.Method + " unknown"
+	}
+	return r.Method + " " + route.GetPathTemplate()
+}
+
+// errorType classifies the outcome of a request.
+func errorType(ctx context.Context, r *http.Request, err error) string {
+	switch {
+	case httpxerrors.IsPanic(err):
+		return ErrorPanic
+	case err == middleware.ErrHandlerTimeout, errors.Is(err, context.DeadlineExceeded), ctx.Err() == context.DeadlineExceeded:
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled), r.Context().Err() == context.Canceled:
+		return ErrorCanceled
+	case err != nil:
+		return ErrorOther
+	}
+	return ErrorNone
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,118 @@
+package metricshttpx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/httpx/middleware"
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/metrics/metricshttpx"
+	"github.com/remind101/pkg/metrics/metricstest"
+)
+
+func TestServerMetrics(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	r := httpx.NewRouter()
+	r.HandleFunc("/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		io.ReadAll(r.Body)
+		io.WriteString(w, "hello")
+		return nil
+	}).Methods("POST")
+	r.HandleFunc("/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}).Methods("GET")
+	r.HandleFunc("/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	}).Methods("GET")
+	r.HandleFunc("/timeout", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return middleware.ErrHandlerTimeout
+	}).Methods("GET")
+	r.HandleFunc("/error", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusInternalServerError)
+		return errors.New("boom")
+	}).Methods("GET")
+
+	h := metricshttpx.NewServerMetrics(middleware.BasicRecover(r), metricshttpx.Opts{
+		SlowThreshold:  time.Hour,
+		SlowThresholds: map[string]time.Duration{"GET /slow": 10 * time.Millisecond},
+	})
+
+	do := func(method, path, body string) {
+		ctx := metrics.WithTags(context.Background(), map[string]string{"tier": "gold"})
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		h.ServeHTTPContext(ctx, httptest.NewRecorder(), req)
+	}
+
+	do("POST", "/users/1", "payload")
+	do("GET", "/slow", "")
+	do("GET", "/panic", "")
+	do("GET", "/timeout", "")
+	do("GET", "/error", "")
+	do("GET", "/missing", "")
+
+	tests := []struct {
+		route, status, class, err string
+	}{
+		{"POST /users/{id}", "200", "2xx", metricshttpx.ErrorNone},
+		{"GET /slow", "200", "2xx", metricshttpx.ErrorNone},
+		{"GET /panic", "500", "5xx", metricshttpx.ErrorPanic},
+		{"GET /timeout", "503", "5xx", metricshttpx.ErrorTimeout},
+		{"GET /error", "500", "5xx", metricshttpx.ErrorOther},
+		{"GET unknown", "404", "4xx", metricshttpx.ErrorNone},
+	}
+	for _, tt := range tests {
+		metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricshttpx.MetricResponseTime, map[string]string{
+			"route":        tt.route,
+			"status":       tt.status,
+			"status_class": tt.class,
+			"error":        tt.err,
+			"tier":         "gold",
+		})
+	}
+
+	route := map[string]string{"route": "POST /users/{id}"}
+	if got, want := reporter.Histograms(metricshttpx.MetricRequestSize, route), []float64{7}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+	if got, want := reporter.Histograms(metricshttpx.MetricResponseSize, route), []float64{5}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+
+	metricstest.AssertCounter(t, reporter, metricshttpx.MetricSlow, nil, 1)
+	metricstest.AssertCounter(t, reporter, metricshttpx.MetricSlow, map[string]string{"route": "GET /slow"}, 1)
+	metricstest.AssertGauge(t, reporter, metricshttpx.MetricInFlight, nil, 0)
+}
+
+func TestServerMetrics_Panic(t *testing.T) {
+	reporter, restore := metricstest.Install()
+	defer restore()
+
+	h := metricshttpx.NewServerMetrics(httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	}), metricshttpx.Opts{})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to propagate")
+			}
+		}()
+		h.ServeHTTPContext(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	metricstest.AssertReported(t, reporter, metricstest.KindTiming, metricshttpx.MetricResponseTime, map[string]string{
+		"status":       "500",
+		"status_class": "5xx",
+		"error":        metricshttpx.ErrorPanic,
+	})
+}
@@ -0,0 +1,136 @@
+package metricstest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AssertCounter fails the test if the Count calls with the given name whose
+// tags include tags don't sum to want.
+func AssertCounter(t testing.TB, r *Reporter, name string, tags map[string]string, want int64) {
+	t.Helper()
+	if got := r.Counter(name, tags); got != want {
+		t.Errorf("counter %s%s: got %d; expected %d\n%s", name, formatTags(tags), got, want, r.dump(name))
+	}
+}
+
+// AssertGauge fails the test if the last Gauge call with the given name whose
+// tags include tags isn't want.
+func AssertGauge(t testing.TB, r *Reporter, name string, tags map[string]string, want float64) {
+	t.Helper()
+	got, ok := r.LastGauge(name, tags)
+	if !ok {
+		t.Errorf("gauge %s%s: not reported; expected %s\n%s", name, formatTags(tags), formatFloat(want), r.dump(name))
+	} else if got != want {
+		t.Errorf("gauge %s%s: got %s; expected %s\n%s", name, formatTags(tags), formatFloat(got), formatFloat(want), r.dump(name))
+	}
+}
+
+// AssertReported fails the test if no call of the given kind and name has
+// tags that include tags.
+func AssertReported(t testing.TB, r *Reporter, kind Kind, name string, tags map[string]string) {
+	t.Helper()
+	if len(r.Find(kind, name, tags)) == 0 {
+		t.Errorf("%s %s%s: not reported\n%s", kind, name, formatTags(tags), r.dump(name))
+	}
+}
+
+// AssertNotReported fails the test if a call of the given kind and name has
+// tags that include tags.
+func AssertNotReported(t testing.TB, r *Reporter, kind Kind, name string, tags map[string]string) {
+	t.Helper()
+	if calls := r.Find(kind, name, tags); len(calls) > 0 {
+		t.Errorf("%s %s%s: reported %d times; expected none\n%s", kind, name, formatTags(tags), len(calls), r.dump(name))
+	}
+}
+
+// AssertCalls fails the test if the recorded calls, in order, aren't want.
+// The failure shows a line diff of the calls, where - lines are expected but
+// missing, and + lines were recorded but not expected.
+func AssertCalls(t testing.TB, r *Reporter, want ...Call) {
+	t.Helper()
+
+	got := r.Calls()
+	gotLines := make([]string, len(got))
+	for i, c := range got {
+		gotLines[i] = c.String()
+	}
+	wantLines := make([]string, len(want))
+	for i, c := range want {
+		wantLines[i] = c.String()
+	}
+
+	if d := diff(wantLines, gotLines); d != "" {
+		t.Errorf("calls differ (-expected +got):\n%s", d)
+	}
+}
+
+// dump formats the recorded calls with the given name, for failure messages.
+func (r *Reporter) dump(name string) string {
+	var b strings.Builder
+	b.WriteString("recorded calls to " + name + ":")
+
+	n := 0
+	for _, c := range r.Calls() {
+		if c.Name == name {
+			b.WriteString("\n\t" + c.String())
+			n++
+		}
+	}
+	if n == 0 {
+		b.WriteString(" none")
+	}
+	return b.String()
+}
+
+// diff returns a line diff of a and b, based on their longest common
+// subsequence, or an empty string if they're equal.
+func diff(a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			out.WriteString("+ " + b[j] + "\n")
+			changed = true
+			j++
+		default:
+			out.WriteString("- " + a[i] + "\n")
+			changed = true
+			i++
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+	return out.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,257 @@
+// Package metricstest provides an in-memory MetricsReporter for testing code
+// that reports metrics.
+//
+// Usage:
+//
+//	func TestSomething(t *testing.T) {
+//		r, restore := metricstest.Install()
+//		defer restore()
+//
+//		// ... exercise code that reports metrics
+//
+//		metricstest.AssertCounter(t, r, "jobs.processed", map[string]string{"queue": "default"}, 1)
+//		if got := len(r.Timings("job.time", nil)); got != 1 {
+//			t.Errorf("got %d timings; expected 1", got)
+//		}
+//	}
+package metricstest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/remind101/pkg/metrics"
+)
+
+// Kind is the MetricsReporter method a Call was recorded from.
+type Kind string
+
+// Kinds of calls.
+const (
+	KindCount        Kind = "count"
+	KindGauge        Kind = "gauge"
+	KindHistogram    Kind = "histogram"
+	KindDistribution Kind = "distribution"
+	KindSet          Kind = "set"
+	KindTiming       Kind = "timing"
+)
+
+// Call is a recorded MetricsReporter call.
+type Call struct {
+	Kind Kind
+	Name string
+
+	// Value is the value of every kind of call but Set.
+	Value float64
+
+	// SetValue is the value of a Set call.
+	SetValue string
+
+	Tags map[string]string
+	Rate float64
+}
+
+// String formats the call on a single line, with sorted tags, like:
+//
+//	count http.requests{route=GET /,status=200} 1 @1
+func (c Call) String() string {
+	v := strconv.FormatFloat(c.Value, 'g', -1, 64)
+	if c.Kind == KindSet {
+		v = strconv.Quote(c.SetValue)
+	}
+	return fmt.Sprintf("%s %s%s %s @%s", c.Kind, c.Name, formatTags(c.Tags), v, strconv.FormatFloat(c.Rate, 'g', -1, 64))
+}
+
+// Reporter is a metrics.MetricsReporter that records every call. It's safe
+// for concurrent use.
+type Reporter struct {
+	mu     sync.Mutex
+	calls  []Call
+	closed bool
+}
+
+var _ metrics.MetricsReporter = (*Reporter)(nil)
+
+// NewReporter returns a new Reporter.
+func NewReporter() *Reporter {
+	return &Reporter{}
+}
+
+// Install sets a new Reporter as metrics.Reporter. The returned function
+// restores the previous one.
+func Install() (*Reporter, func()) {
+	old := metrics.Reporter
+	r := NewReporter()
+	metrics.Reporter = r
+	return r, func() {
+		metrics.Reporter = old
+	}
+}
+
+func (r *Reporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindCount, Name: name, Value: float64(value), Tags: tags, Rate: rate})
+}
+
+func (r *Reporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindGauge, Name: name, Value: value, Tags: tags, Rate: rate})
+}
+
+func (r *Reporter) Histogram(name string, value float64, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindHistogram, Name: name, Value: value, Tags: tags, Rate: rate})
+}
+
+func (r *Reporter) Distribution(name string, value float64, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindDistribution, Name: name, Value: value, Tags: tags, Rate: rate})
+}
+
+func (r *Reporter) Set(name string, value string, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindSet, Name: name, SetValue: value, Tags: tags, Rate: rate})
+}
+
+func (r *Reporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
+	return r.record(Call{Kind: KindTiming, Name: name, Value: value, Tags: tags, Rate: rate})
+}
+
+// Close marks the reporter closed. Calls are still recorded.
+func (r *Reporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// Closed reports whether Close was called.
+func (r *Reporter) Closed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+func (r *Reporter) record(c Call) error {
+	// Copy the tags, callers may reuse the map.
+	tags := make(map[string]string, len(c.Tags))
+	for k, v := range c.Tags {
+		tags[k] = v
+	}
+	c.Tags = tags
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+	return nil
+}
+
+// Calls returns every recorded call, in order.
+func (r *Reporter) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Reset forgets the recorded calls.
+func (r *Reporter) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+	r.closed = false
+}
+
+// Find returns the calls of the given kind and name whose tags include tags.
+// A nil tags matches every call.
+func (r *Reporter) Find(kind Kind, name string, tags map[string]string) []Call {
+	var calls []Call
+	for _, c := range r.Calls() {
+		if c.Kind == kind && c.Name == name && hasTags(c.Tags, tags) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Counter returns the sum of the Count calls with the given name whose tags
+// include tags.
+func (r *Reporter) Counter(name string, tags map[string]string) int64 {
+	var n int64
+	for _, c := range r.Find(KindCount, name, tags) {
+		n += int64(c.Value)
+	}
+	return n
+}
+
+// LastGauge returns the value of the last Gauge call with the given name whose
+// tags include tags, and whether there was one.
+func (r *Reporter) LastGauge(name string, tags map[string]string) (float64, bool) {
+	calls := r.Find(KindGauge, name, tags)
+	if len(calls) == 0 {
+		return 0, false
+	}
+	return calls[len(calls)-1].Value, true
+}
+
+// Timings returns the values of the TimeInMilliseconds calls with the given
+// name whose tags include tags.
+func (r *Reporter) Timings(name string, tags map[string]string) []float64 {
+	return values(r.Find(KindTiming, name, tags))
+}
+
+// Histograms returns the values of the Histogram calls with the given name
+// whose tags include tags.
+func (r *Reporter) Histograms(name string, tags map[string]string) []float64 {
+	return values(r.Find(KindHistogram, name, tags))
+}
+
+// Distributions returns the values of the Distribution calls with the given
+// name whose tags include tags.
+func (r *Reporter) Distributions(name string, tags map[string]string) []float64 {
+	return values(r.Find(KindDistribution, name, tags))
+}
+
+// SetValues returns the distinct values of the Set calls with the given name
+// whose tags include tags, sorted.
+func (r *Reporter) SetValues(name string, tags map[string]string) []string {
+	seen := make(map[string]bool)
+	var vs []string
+	for _, c := range r.Find(KindSet, name, tags) {
+		if !seen[c.SetValue] {
+			seen[c.SetValue] = true
+			vs = append(vs, c.SetValue)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func values(calls []Call) []float64 {
+	var vs []float64
+	for _, c := range calls {
+		vs = append(vs, c.Value)
+	}
+	return vs
+}
+
+// hasTags reports whether tags includes every tag of want.
+func hasTags(tags, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := tags[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
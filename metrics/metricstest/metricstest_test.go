@@ -0,0 +1,94 @@
+package metricstest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/remind101/pkg/metrics"
+)
+
+func TestReporter(t *testing.T) {
+	r, restore := Install()
+	defer restore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status := "200"
+			if i%2 == 0 {
+				status = "500"
+			}
+			metrics.Count("requests", 1, map[string]string{"status": status}, 1.0)
+			metrics.TimeInMilliseconds("request.time", float64(i), nil, 1.0)
+		}(i)
+	}
+	wg.Wait()
+
+	tags := map[string]string{"user": "a"}
+	metrics.Set("users", "b", tags, 1.0)
+	tags["user"] = "b"
+	metrics.Set("users", "a", tags, 1.0)
+	metrics.Gauge("queue.depth", 1, nil, 1.0)
+	metrics.Gauge("queue.depth", 2, nil, 1.0)
+
+	AssertCounter(t, r, "requests", nil, 10)
+	AssertCounter(t, r, "requests", map[string]string{"status": "500"}, 5)
+	AssertGauge(t, r, "queue.depth", nil, 2)
+	AssertReported(t, r, KindSet, "users", map[string]string{"user": "a"})
+	AssertNotReported(t, r, KindCount, "requests", map[string]string{"status": "404"})
+
+	if got, want := len(r.Timings("request.time", nil)), 10; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+	if got, want := r.SetValues("users", nil), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+
+	restore()
+	if metrics.Reporter == metrics.MetricsReporter(r) {
+		t.Error("expected the previous reporter to be restored")
+	}
+}
+
+func TestAssertCalls(t *testing.T) {
+	r := NewReporter()
+	r.Count("requests", 1, map[string]string{"status": "200"}, 1.0)
+	r.Gauge("queue.depth", 3, nil, 1.0)
+
+	ft := &fakeT{}
+	AssertCalls(ft, r,
+		Call{Kind: KindCount, Name: "requests", Value: 1, Tags: map[string]string{"status": "200"}, Rate: 1},
+		Call{Kind: KindGauge, Name: "queue.depth", Value: 2, Rate: 1},
+	)
+
+	want := `calls differ (-expected +got):
+  count requests{status=200} 1 @1
++ gauge queue.depth 3 @1
+- gauge queue.depth 2 @1
+`
+	if got := ft.msg; got != want {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, want)
+	}
+
+	ft = &fakeT{}
+	AssertCounter(ft, r, "requests", nil, 2)
+	if !strings.Contains(ft.msg, "got 1; expected 2\nrecorded calls to requests:\n\tcount requests{status=200} 1 @1") {
+		t.Errorf("got %s", ft.msg)
+	}
+}
+
+type fakeT struct {
+	testing.TB
+	msg string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.msg += fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MultiReporter is a MetricsReporter that sends metrics to multiple
+// MetricsReporters, for instance to migrate from one backend to another. Every
+// reporter is called, even if some fail, and their errors are returned joined
+// with errors.Join.
+type MultiReporter []MetricsReporter
+
+func (r MultiReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.Count(name, value, tags, rate) })
+}
+
+func (r MultiReporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.Gauge(name, value, tags, rate) })
+}
+
+func (r MultiReporter) Histogram(name string, value float64, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.Histogram(name, value, tags, rate) })
+}
+
+func (r MultiReporter) Distribution(name string, value float64, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.Distribution(name, value, tags, rate) })
+}
+
+func (r MultiReporter) Set(name string, value string, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.Set(name, value, tags, rate) })
+}
+
+func (r MultiReporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
+	return r.each(func(m MetricsReporter) error { return m.TimeInMilliseconds(name, value, tags, rate) })
+}
+
+func (r MultiReporter) Close() error {
+	return r.each(func(m MetricsReporter) error { return m.Close() })
+}
+
+func (r MultiReporter) each(fn func(MetricsReporter) error) error {
+	var errs []error
+	for _, m := range r {
+		if err := fn(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handler returns the first reporter that is an http.Handler, like a
+// PrometheusMetricsReporter, looking into nested MultiReporters, or nil if
+// there's none.
+func (r MultiReporter) Handler() http.Handler {
+	for _, m := range r {
+		switch m := m.(type) {
+		case http.Handler:
+			return m
+		case MultiReporter:
+			if h := m.Handler(); h != nil {
+				return h
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+)
+
+type errMetricsReporter struct {
+	NoopMetricsReporter
+	err error
+}
+
+func (r *errMetricsReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	return r.err
+}
+
+func TestMultiReporter(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	fake := newFakeMetricsReporter()
+	r := MultiReporter{&errMetricsReporter{err: errA}, fake, &errMetricsReporter{err: errB}}
+
+	err := r.Count("requests", 1, nil, 1.0)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("got %v; expected both errors", err)
+	}
+	if fake.LastCountMetric == nil {
+		t.Error("expected every reporter to be called")
+	}
+
+	must(t, r.Gauge("queue.depth", 1, nil, 1.0))
+}
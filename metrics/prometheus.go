@@ -0,0 +1,428 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prometheus metric types, as written in the text exposition format.
+const (
+	promCounter   = "counter"
+	promGauge     = "gauge"
+	promHistogram = "histogram"
+	promSummary   = "summary"
+)
+
+// Defaults for PrometheusOpts.
+var (
+	// DefaultPrometheusBuckets are the buckets for Histogram.
+	DefaultPrometheusBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultPrometheusTimingBuckets are the buckets for TimeInMilliseconds.
+	DefaultPrometheusTimingBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+	// DefaultPrometheusQuantiles are the quantiles for Distribution.
+	DefaultPrometheusQuantiles = []float64{0.5, 0.9, 0.99}
+)
+
+// PrometheusOpts configures a PrometheusMetricsReporter.
+type PrometheusOpts struct {
+	// Namespace is prepended to every metric name, separated by an
+	// underscore.
+	Namespace string
+
+	// Buckets are the upper bounds of the Histogram buckets.
+	Buckets []float64
+
+	// TimingBuckets are the upper bounds of the TimeInMilliseconds buckets.
+	TimingBuckets []float64
+
+	// Quantiles are the quantiles reported for Distribution.
+	Quantiles []float64
+
+	// MaxSeries bounds the number of label sets per metric. Calls with new
+	// label sets beyond it are dropped. Zero means 1000.
+	MaxSeries int
+
+	// MaxSamples bounds the number of recent samples used to compute the
+	// quantiles of each Distribution series, and the number of distinct
+	// values tracked by each Set series. Zero means 1024.
+	MaxSamples int
+}
+
+// PrometheusMetricsReporter is a MetricsReporter that keeps metrics in memory
+// and serves them in the Prometheus text exposition format, as an
+// http.Handler.
+//
+// Count is a counter, Gauge a gauge, Histogram and TimeInMilliseconds are
+// histograms, Distribution is a summary, and Set is a gauge of the number of
+// distinct values seen. Tags become labels. Metric names and tag keys are
+// sanitized to be valid Prometheus names. Sample rates are ignored, since every
+// call is recorded.
+type PrometheusMetricsReporter struct {
+	opts PrometheusOpts
+
+	mu       sync.Mutex
+	families map[string]*promFamily
+	dropped  uint64
+}
+
+// NewPrometheusMetricsReporter returns a PrometheusMetricsReporter.
+func NewPrometheusMetricsReporter(opts PrometheusOpts) *PrometheusMetricsReporter {
+	if opts.Buckets == nil {
+		opts.Buckets = DefaultPrometheusBuckets
+	}
+	if opts.TimingBuckets == nil {
+		opts.TimingBuckets = DefaultPrometheusTimingBuckets
+	}
+	if opts.Quantiles == nil {
+		opts.Quantiles = DefaultPrometheusQuantiles
+	}
+	if opts.MaxSeries == 0 {
+		opts.MaxSeries = 1000
+	}
+	if opts.MaxSamples == 0 {
+		opts.MaxSamples = 1024
+	}
+	return &PrometheusMetricsReporter{
+		opts:     opts,
+		families: make(map[string]*promFamily),
+	}
+}
+
+type promFamily struct {
+	name    string
+	typ     string
+	kind    string
+	buckets []float64
+	series  map[string]*promSeries
+}
+
+type promSeries struct {
+	labels string
+
+	// counter and gauge
+	value float64
+
+	// histogram and summary
+	counts  []uint64
+	sum     float64
+	count   uint64
+	samples []float64
+	next    int
+
+	// set
+	values map[string]struct{}
+}
+
+func (r *PrometheusMetricsReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return r.observe("counter", name, promCounter, nil, tags, func(s *promSeries) {
+		s.value += float64(value)
+	})
+}
+
+func (r *PrometheusMetricsReporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	return r.observe("gauge", name, promGauge, nil, tags, func(s *promSeries) {
+		s.value = value
+	})
+}
+
+func (r *PrometheusMetricsReporter) Histogram(name string, value float64, tags map[string]string, rate float64) error {
+	return r.observe("histogram", name, promHistogram, r.opts.Buckets, tags, func(s *promSeries) {
+		s.observeBucket(r.opts.Buckets, value)
+	})
+}
+
+func (r *PrometheusMetricsReporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
+	return r.observe("timing", name, promHistogram, r.opts.TimingBuckets, tags, func(s *promSeries) {
+		s.observeBucket(r.opts.TimingBuckets, value)
+	})
+}
+
+func (r *PrometheusMetricsReporter) Distribution(name string, value float64, tags map[string]string, rate float64) error {
+	return r.observe("distribution", name, promSummary, nil, tags, func(s *promSeries) {
+		s.sum += value
+		s.count++
+		if len(s.samples) < r.opts.MaxSamples {
+			s.samples = append(s.samples, value)
+		} else {
+			s.samples[s.next] = value
+			s.next = (s.next + 1) % len(s.samples)
+		}
+	})
+}
+
+func (r *PrometheusMetricsReporter) Set(name string, value string, tags map[string]string, rate float64) error {
+	return r.observe("set", name, promGauge, nil, tags, func(s *promSeries) {
+		if s.values == nil {
+			s.values = make(map[string]struct{})
+		}
+		if len(s.values) < r.opts.MaxSamples {
+			s.values[value] = struct{}{}
+		}
+		s.value = float64(len(s.values))
+	})
+}
+
+func (r *PrometheusMetricsReporter) Close() error {
+	return nil
+}
+
+// Dropped returns the number of calls that were dropped because their metric
+// had too many label sets, or was reported with different types.
+func (r *PrometheusMetricsReporter) Dropped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// observe finds or creates the series for name and tags, and calls fn with it.
+// kind is the method reporting it, so that a metric is only reported by one.
+func (r *PrometheusMetricsReporter) observe(kind, name, typ string, buckets []float64, tags map[string]string, fn func(*promSeries)) error {
+	name = SanitizePrometheusName(name)
+	if r.opts.Namespace != "" {
+		name = SanitizePrometheusName(r.opts.Namespace) + "_" + name
+	}
+	labels := formatPromLabels(tags)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &promFamily{
+			name:    name,
+			typ:     typ,
+			kind:    kind,
+			buckets: buckets,
+			series:  make(map[string]*promSeries),
+		}
+		r.families[name] = f
+	} else if f.typ != typ || f.kind != kind || !equalBuckets(f.buckets, buckets) {
+		r.dropped++
+		return fmt.Errorf("metrics: %s is already registered as a %s", name, f.kind)
+	}
+
+	s, ok := f.series[labels]
+	if !ok {
+		if len(f.series) >= r.opts.MaxSeries {
+			r.dropped++
+			return fmt.Errorf("metrics: %s has too many label sets", name)
+		}
+		s = &promSeries{labels: labels}
+		if buckets != nil {
+			s.counts = make([]uint64, len(buckets))
+		}
+		f.series[labels] = s
+	}
+
+	fn(s)
+	return nil
+}
+
+func equalBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *promSeries) observeBucket(buckets []float64, value float64) {
+	s.sum += value
+	s.count++
+	for i, upper := range buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (r *PrometheusMetricsReporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (r *PrometheusMetricsReporter) WriteText(out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+		labels := make([]string, 0, len(f.series))
+		for l := range f.series {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		for _, l := range labels {
+			f.write(w, f.series[l], r.opts.Quantiles)
+		}
+	}
+
+	w.WriteString("# TYPE metrics_prometheus_dropped_total counter\n")
+	fmt.Fprintf(w, "metrics_prometheus_dropped_total %d\n", r.dropped)
+
+	return w.Flush()
+}
+
+func (f *promFamily) write(w *bufio.Writer, s *promSeries, quantiles []float64) {
+	switch f.typ {
+	case promCounter, promGauge:
+		writePromSample(w, f.name, s.labels, "", s.value)
+	case promHistogram:
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			writePromSample(w, f.name+"_bucket", s.labels, `le="`+formatPromFloat(upper)+`"`, float64(cumulative))
+		}
+		writePromSample(w, f.name+"_bucket", s.labels, `le="+Inf"`, float64(s.count))
+		writePromSample(w, f.name+"_sum", s.labels, "", s.sum)
+		writePromSample(w, f.name+"_count", s.labels, "", float64(s.count))
+	case promSummary:
+		sorted := append([]float64(nil), s.samples...)
+		sort.Float64s(sorted)
+		for _, q := range quantiles {
+			writePromSample(w, f.name, s.labels, `quantile="`+formatPromFloat(q)+`"`, quantile(sorted, q))
+		}
+		writePromSample(w, f.name+"_sum", s.labels, "", s.sum)
+		writePromSample(w, f.name+"_count", s.labels, "", float64(s.count))
+	}
+}
+
+func writePromSample(w *bufio.Writer, name, labels, extra string, value float64) {
+	w.WriteString(name)
+	if labels != "" || extra != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		if labels != "" && extra != "" {
+			w.WriteByte(',')
+		}
+		w.WriteString(extra)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatPromFloat(value))
+	w.WriteByte('\n')
+}
+
+// quantile returns the q quantile of sorted values, using the nearest rank.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func formatPromFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatPromLabels formats tags as sorted, escaped, Prometheus labels. When
+// several tags sanitize to the same label, like "a.b" and "a_b", the one that
+// sorts first is kept.
+func formatPromLabels(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	values := make(map[string]string, len(tags))
+	origins := make(map[string]string, len(tags))
+	for k, v := range tags {
+		label := sanitizePromLabel(k)
+		if o, ok := origins[label]; ok {
+			if k < o {
+				origins[label], values[label] = k, v
+			}
+			continue
+		}
+		keys = append(keys, label)
+		origins[label], values[label] = k, v
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString(`="`)
+		b.WriteString(promLabelEscaper.Replace(values[k]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// SanitizePrometheusName replaces the characters that are not allowed in
+// Prometheus metric names, like dots, with underscores.
+func SanitizePrometheusName(name string) string {
+	return sanitizeProm(name, true)
+}
+
+// sanitizePromLabel sanitizes a label name. Names starting with __ are
+// reserved by Prometheus.
+func sanitizePromLabel(name string) string {
+	name = sanitizeProm(name, false)
+	if strings.HasPrefix(name, "__") {
+		name = "tag" + name[1:]
+	}
+	return name
+}
+
+func sanitizeProm(name string, allowColon bool) string {
+	if name == "" || '0' <= name[0] && name[0] <= '9' {
+		name = "_" + name
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+			'0' <= c && c <= '9' || allowColon && c == ':'
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
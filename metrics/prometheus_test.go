@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusMetricsReporter(t *testing.T) {
+	r := NewPrometheusMetricsReporter(PrometheusOpts{
+		Namespace: "app",
+		Buckets:   []float64{1, 10},
+		Quantiles: []float64{0.5, 1},
+	})
+
+	must(t, r.Count("http.requests", 1, map[string]string{"status": "200", "route": "/users"}, 1.0))
+	must(t, r.Count("http.requests", 1, map[string]string{"route": "/users", "status": "200"}, 0.5))
+	must(t, r.Gauge("queue.depth", 3, nil, 1.0))
+	must(t, r.Gauge("queue.depth", 5, nil, 1.0))
+	must(t, r.Histogram("payload", 0.5, nil, 1.0))
+	must(t, r.Histogram("payload", 20, nil, 1.0))
+	must(t, r.Distribution("latency", 1, nil, 1.0))
+	must(t, r.Distribution("latency", 2, nil, 1.0))
+	must(t, r.Distribution("latency", 3, nil, 1.0))
+	must(t, r.Set("users", "a", nil, 1.0))
+	must(t, r.Set("users", "b", nil, 1.0))
+	must(t, r.Set("users", "a", nil, 1.0))
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got, want := resp.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+
+	want := `# TYPE app_http_requests_total counter
+app_http_requests_total{route="/users",status="200"} 2
+# TYPE app_latency summary
+app_latency{quantile="0.5"} 2
+app_latency{quantile="1"} 3
+app_latency_sum 6
+app_latency_count 3
+# TYPE app_payload histogram
+app_payload_bucket{le="1"} 1
+app_payload_bucket{le="10"} 1
+app_payload_bucket{le="+Inf"} 2
+app_payload_sum 20.5
+app_payload_count 2
+# TYPE app_queue_depth gauge
+app_queue_depth 5
+# TYPE app_users gauge
+app_users 2
+# TYPE metrics_prometheus_dropped_total counter
+metrics_prometheus_dropped_total 0
+`
+	if got := resp.Body.String(); got != want {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+func TestPrometheusMetricsReporter_Limits(t *testing.T) {
+	r := NewPrometheusMetricsReporter(PrometheusOpts{MaxSeries: 2})
+
+	must(t, r.Gauge("gauge", 1, map[string]string{"id": "1"}, 1.0))
+	must(t, r.Gauge("gauge", 1, map[string]string{"id": "2"}, 1.0))
+	must(t, r.Gauge("gauge", 2, map[string]string{"id": "2"}, 1.0))
+	if err := r.Gauge("gauge", 1, map[string]string{"id": "3"}, 1.0); err == nil {
+		t.Error("expected an error past MaxSeries")
+	}
+	if err := r.Histogram("gauge", 1, nil, 1.0); err == nil {
+		t.Error("expected an error when the type changes")
+	}
+	if err := r.Set("gauge", "a", nil, 1.0); err == nil {
+		t.Error("expected an error when the kind changes")
+	}
+	must(t, r.Histogram("latency", 1, nil, 1.0))
+	if err := r.TimeInMilliseconds("latency", 1, nil, 1.0); err == nil {
+		t.Error("expected an error when the buckets change")
+	}
+
+	if got, want := r.Dropped(), uint64(4); got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+}
+
+func TestPrometheusMetricsReporter_Escaping(t *testing.T) {
+	r := NewPrometheusMetricsReporter(PrometheusOpts{})
+
+	must(t, r.Gauge("5xx.rate-1m", 1, map[string]string{
+		"empire.app.name": "api",
+		"__name__":        "x",
+		"msg":             "a \"quoted\"\\path\nline",
+	}, 1.0))
+
+	var b strings.Builder
+	must(t, r.WriteText(&b))
+
+	want := `_5xx_rate_1m{empire_app_name="api",msg="a \"quoted\"\\path\nline",tag_name__="x"} 1`
+	if !strings.Contains(b.String(), want+"\n") {
+		t.Errorf("expected output to contain %s, got:\n%s", want, b.String())
+	}
+}
+
+func TestPrometheusMetricsReporter_DuplicateLabels(t *testing.T) {
+	r := NewPrometheusMetricsReporter(PrometheusOpts{})
+
+	for i := 0; i < 10; i++ {
+		must(t, r.Count("requests_total", 1, map[string]string{"a.b": "1", "a_b": "2"}, 1.0))
+	}
+
+	var b strings.Builder
+	must(t, r.WriteText(&b))
+
+	want := `requests_total{a_b="1"} 10`
+	if !strings.Contains(b.String(), want+"\n") {
+		t.Errorf("expected output to contain %s, got:\n%s", want, b.String())
+	}
+}
+
+func TestSanitizePrometheusName(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"http.requests", "http_requests"},
+		{"ns:requests", "ns:requests"},
+		{"1m", "_1m"},
+		{"", "_"},
+		{"caf\u00e9", "caf__"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizePrometheusName(tt.in); got != tt.out {
+			t.Errorf("SanitizePrometheusName(%q) = %q; expected %q", tt.in, got, tt.out)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 var RuntimeMetricsSamplingInterval time.Duration = 30 * time.Second
 
 // Runtime enters into a loop, sampling and outputing the runtime stats periodically.
+// See RuntimeCollector for a loop that can be stopped, and doesn't stop the
+// world to read the stats.
 // Usage:
 //   func main() {
 //     ...
@@ -25,6 +27,14 @@ func Runtime() {
 
 // Samples and reports current runtime status once.
 func ReportRuntimeMetrics() {
+	for name, value := range RuntimeStats() {
+		Gauge(name, value, nil, 1.0)
+	}
+}
+
+// RuntimeStats samples the current runtime status, keyed by the metric names
+// used by ReportRuntimeMetrics.
+func RuntimeStats() map[string]float64 {
 	var memstats runtime.MemStats
 	runtime.ReadMemStats(&memstats)
 	numGoroutines := runtime.NumGoroutine()
@@ -51,7 +61,5 @@ func ReportRuntimeMetrics() {
 		"runtime.MemStats.StackInuse":   float64(memstats.StackInuse),
 	}
 
-	for name, value := range r {
-		Gauge(name, value, nil, 1.0)
-	}
+	return r
 }
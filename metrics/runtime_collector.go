@@ -0,0 +1,382 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	rtmetrics "runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// RuntimeGroup selects groups of metrics reported by a RuntimeCollector. Groups
+// can be combined with |.
+type RuntimeGroup int
+
+const (
+	// RuntimeGC reports GC cycles, stop-the-world pause quantiles and the
+	// heap goal.
+	RuntimeGC RuntimeGroup = 1 << iota
+
+	// RuntimeMemory reports the heap objects, in count and bytes, and the
+	// memory mapped by the runtime.
+	RuntimeMemory
+
+	// RuntimeScheduler reports goroutines, GOMAXPROCS and the quantiles of
+	// the time goroutines spend runnable before running.
+	RuntimeScheduler
+
+	// RuntimeSync reports the time goroutines spend blocked on mutexes.
+	RuntimeSync
+
+	// RuntimeProcess reports the CPU usage, resident memory, threads and
+	// open file descriptors of the process.
+	RuntimeProcess
+
+	// RuntimeMemStats reports the metrics of ReportRuntimeMetrics, like
+	// goroutine and runtime.MemStats.HeapAlloc, which stop the world to be
+	// read. It's deprecated, and only meant for dashboards and monitors
+	// that haven't moved to the metrics of the other groups yet.
+	RuntimeMemStats
+
+	// RuntimeDefault are the groups reported when none are selected.
+	RuntimeDefault = RuntimeGC | RuntimeMemory | RuntimeScheduler | RuntimeSync
+)
+
+var runtimeGroupNames = map[string]RuntimeGroup{
+	"gc":        RuntimeGC,
+	"memory":    RuntimeMemory,
+	"scheduler": RuntimeScheduler,
+	"sync":      RuntimeSync,
+	"process":   RuntimeProcess,
+	"memstats":  RuntimeMemStats,
+}
+
+// ParseRuntimeGroups parses comma separated group names: gc, memory,
+// scheduler, sync, process and memstats.
+func ParseRuntimeGroups(s string) (RuntimeGroup, error) {
+	var g RuntimeGroup
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		group, ok := runtimeGroupNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown runtime metrics group: %s", name)
+		}
+		g |= group
+	}
+	return g, nil
+}
+
+// String returns the comma separated names of the groups.
+func (g RuntimeGroup) String() string {
+	var names []string
+	for _, name := range []string{"gc", "memory", "scheduler", "sync", "process", "memstats"} {
+		if g&runtimeGroupNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (g RuntimeGroup) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, with ParseRuntimeGroups.
+func (g *RuntimeGroup) UnmarshalText(b []byte) error {
+	parsed, err := ParseRuntimeGroups(string(b))
+	if err != nil {
+		return err
+	}
+	*g = parsed
+	return nil
+}
+
+// RuntimeCollectorOpts configures a RuntimeCollector.
+type RuntimeCollectorOpts struct {
+	// Interval is how often Run reports metrics. Zero means
+	// RuntimeMetricsSamplingInterval.
+	Interval time.Duration
+
+	// Groups are the groups of metrics to report. Zero means
+	// RuntimeDefault.
+	Groups RuntimeGroup
+
+	// Reporter receives the metrics. Nil means the package level
+	// functions, which add the default tags.
+	Reporter MetricsReporter
+}
+
+// RuntimeCollector reports runtime metrics read from the runtime/metrics
+// package, which, unlike runtime.ReadMemStats, doesn't stop the world, and
+// process metrics.
+//
+// Cumulative values, like GC cycles, are reported as counts of their change
+// since the last sample. Latency distributions, like GC pauses, are reported
+// as gauges of the p50, p99 and max of the samples since the last sample, in
+// milliseconds.
+type RuntimeCollector struct {
+	opts RuntimeCollectorOpts
+
+	samples []rtmetrics.Sample
+	hists   map[string]*rtmetrics.Float64Histogram
+	counts  map[string]int64
+	proc    *process.Process
+	cpu     float64
+	cpuAt   time.Time
+}
+
+// Runtime metrics read by the collector. The GC pauses metric was renamed in
+// Go 1.22, the first one supported by the runtime is used.
+var (
+	runtimeGCPauses = []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}
+
+	runtimeMetricNames = map[RuntimeGroup][]string{
+		RuntimeGC: {
+			"/gc/cycles/total:gc-cycles",
+			"/gc/heap/goal:bytes",
+		},
+		RuntimeMemory: {
+			"/gc/heap/objects:objects",
+			"/memory/classes/heap/objects:bytes",
+			"/memory/classes/total:bytes",
+		},
+		RuntimeScheduler: {
+			"/sched/goroutines:goroutines",
+			"/sched/gomaxprocs:threads",
+			"/sched/latencies:seconds",
+		},
+		RuntimeSync: {
+			"/sync/mutex/wait/total:seconds",
+		},
+	}
+)
+
+// NewRuntimeCollector returns a RuntimeCollector.
+func NewRuntimeCollector(opts RuntimeCollectorOpts) *RuntimeCollector {
+	if opts.Interval == 0 {
+		opts.Interval = RuntimeMetricsSamplingInterval
+	}
+	if opts.Groups == 0 {
+		opts.Groups = RuntimeDefault
+	}
+
+	supported := make(map[string]bool)
+	for _, d := range rtmetrics.All() {
+		supported[d.Name] = true
+	}
+
+	var names []string
+	for _, group := range []RuntimeGroup{RuntimeGC, RuntimeMemory, RuntimeScheduler, RuntimeSync} {
+		if opts.Groups&group == 0 {
+			continue
+		}
+		if group == RuntimeGC {
+			for _, name := range runtimeGCPauses {
+				if supported[name] {
+					names = append(names, name)
+					break
+				}
+			}
+		}
+		for _, name := range runtimeMetricNames[group] {
+			if supported[name] {
+				names = append(names, name)
+			}
+		}
+	}
+
+	c := &RuntimeCollector{
+		opts:    opts,
+		samples: make([]rtmetrics.Sample, len(names)),
+		hists:   make(map[string]*rtmetrics.Float64Histogram),
+		counts:  make(map[string]int64),
+	}
+	for i, name := range names {
+		c.samples[i].Name = name
+	}
+
+	if opts.Groups&RuntimeProcess != 0 {
+		// Process metrics are skipped if the process can't be inspected.
+		c.proc, _ = process.NewProcess(int32(os.Getpid()))
+	}
+
+	return c
+}
+
+// Run reports metrics every Interval until ctx is done.
+func (c *RuntimeCollector) Run(ctx context.Context) error {
+	t := time.NewTicker(c.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			c.Collect()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Collect samples and reports the metrics once.
+func (c *RuntimeCollector) Collect() {
+	rtmetrics.Read(c.samples)
+
+	for _, s := range c.samples {
+		name := runtimeMetricName(s.Name)
+
+		switch s.Value.Kind() {
+		case rtmetrics.KindUint64:
+			v := s.Value.Uint64()
+			if isCumulative(s.Name) {
+				c.count(name, int64(v))
+			} else {
+				c.gauge(name, float64(v))
+			}
+		case rtmetrics.KindFloat64:
+			// Cumulative durations in seconds, reported as counts of
+			// milliseconds.
+			v := s.Value.Float64()
+			if isCumulative(s.Name) {
+				c.count(name+"_ms", int64(math.Floor(v*1000)))
+			} else {
+				c.gauge(name, v)
+			}
+		case rtmetrics.KindFloat64Histogram:
+			c.histogram(name, s.Name, s.Value.Float64Histogram())
+		}
+	}
+
+	if c.proc != nil {
+		c.collectProcess()
+	}
+
+	if c.opts.Groups&RuntimeMemStats != 0 {
+		for name, value := range RuntimeStats() {
+			c.gauge(name, value)
+		}
+	}
+}
+
+func (c *RuntimeCollector) collectProcess() {
+	if mem, err := c.proc.MemoryInfo(); err == nil {
+		c.gauge("process.rss", float64(mem.RSS))
+	}
+	if n, err := c.proc.NumThreads(); err == nil {
+		c.gauge("process.threads", float64(n))
+	}
+	if n, err := c.proc.NumFDs(); err == nil {
+		c.gauge("process.open_fds", float64(n))
+	}
+	if times, err := c.proc.Times(); err == nil {
+		now := time.Now()
+		cpu := times.User + times.System
+		c.count("process.cpu_ms", int64(math.Floor(cpu*1000)))
+		if !c.cpuAt.IsZero() {
+			// The percentage of one CPU used since the last sample.
+			c.gauge("process.cpu_percent", 100*(cpu-c.cpu)/now.Sub(c.cpuAt).Seconds())
+		}
+		c.cpu, c.cpuAt = cpu, now
+	}
+}
+
+// count reports the change of a cumulative value since the last sample. The
+// first sample is only recorded.
+func (c *RuntimeCollector) count(name string, total int64) {
+	last, ok := c.counts[name]
+	c.counts[name] = total
+	if ok && total > last {
+		if c.opts.Reporter != nil {
+			c.opts.Reporter.Count(name, total-last, nil, 1.0)
+		} else {
+			Count(name, total-last, nil, 1.0)
+		}
+	}
+}
+
+func (c *RuntimeCollector) gauge(name string, value float64) {
+	if c.opts.Reporter != nil {
+		c.opts.Reporter.Gauge(name, value, nil, 1.0)
+	} else {
+		Gauge(name, value, nil, 1.0)
+	}
+}
+
+// histogram reports the p50, p99 and max, in milliseconds, of the samples
+// added to a cumulative histogram since the last sample.
+func (c *RuntimeCollector) histogram(name, key string, h *rtmetrics.Float64Histogram) {
+	last, ok := c.hists[key]
+
+	// The histogram is reused by the next Read, keep a copy.
+	cp := &rtmetrics.Float64Histogram{
+		Counts:  append([]uint64(nil), h.Counts...),
+		Buckets: h.Buckets,
+	}
+	c.hists[key] = cp
+	if !ok {
+		return
+	}
+
+	delta := make([]uint64, len(cp.Counts))
+	var total uint64
+	for i := range cp.Counts {
+		delta[i] = cp.Counts[i] - last.Counts[i]
+		total += delta[i]
+	}
+	if total == 0 {
+		return
+	}
+
+	for _, q := range []struct {
+		suffix string
+		q      float64
+	}{{".p50", 0.5}, {".p99", 0.99}, {".max", 1}} {
+		c.gauge(name+q.suffix+"_ms", histogramQuantile(delta, cp.Buckets, total, q.q)*1000)
+	}
+}
+
+// histogramQuantile returns the upper bound of the bucket holding the q
+// quantile. Infinite bounds are replaced by the lower bound of the bucket.
+func histogramQuantile(counts []uint64, buckets []float64, total uint64, q float64) float64 {
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for i, n := range counts {
+		cumulative += n
+		if cumulative >= rank {
+			if upper := buckets[i+1]; !math.IsInf(upper, 1) {
+				return upper
+			}
+			return buckets[i]
+		}
+	}
+	return buckets[len(buckets)-1]
+}
+
+// isCumulative reports whether a runtime/metrics value only increases.
+func isCumulative(name string) bool {
+	return strings.Contains(name, "/total:") && !strings.HasPrefix(name, "/memory/")
+}
+
+// runtimeMetricName converts a runtime/metrics name, like
+// /gc/heap/goal:bytes, to a metric name, like runtime.gc.heap.goal.
+func runtimeMetricName(name string) string {
+	for _, pauses := range runtimeGCPauses {
+		if name == pauses {
+			return "runtime.gc.pauses"
+		}
+	}
+	name, _, _ = strings.Cut(name, ":")
+	return "runtime" + strings.NewReplacer("/", ".", "-", "_").Replace(name)
+}
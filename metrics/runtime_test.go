@@ -1,7 +1,78 @@
 package metrics
 
-import "testing"
+import (
+	"context"
+	"runtime"
+	"testing"
+)
 
 func TestRuntime(t *testing.T) {
 	ReportRuntimeMetrics()
 }
+
+type namesMetricsReporter struct {
+	NoopMetricsReporter
+	names map[string]bool
+}
+
+func (r *namesMetricsReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	r.names[name] = true
+	return nil
+}
+
+func (r *namesMetricsReporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	r.names[name] = true
+	return nil
+}
+
+func TestRuntimeCollector(t *testing.T) {
+	r := &namesMetricsReporter{names: make(map[string]bool)}
+	c := NewRuntimeCollector(RuntimeCollectorOpts{
+		Groups:   RuntimeGC | RuntimeScheduler | RuntimeProcess | RuntimeMemStats,
+		Reporter: r,
+	})
+
+	c.Collect()
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+	}
+	c.Collect()
+
+	for _, name := range []string{
+		"runtime.gc.cycles.total",
+		"runtime.gc.heap.goal",
+		"runtime.gc.pauses.p99_ms",
+		"runtime.sched.goroutines",
+		"process.rss",
+		"process.cpu_percent",
+		"goroutine",
+		"runtime.MemStats.HeapAlloc",
+	} {
+		if !r.names[name] {
+			t.Errorf("expected %s to be reported, got %v", name, r.names)
+		}
+	}
+	if r.names["runtime.memory.classes.total"] {
+		t.Error("expected the memory group not to be reported")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Run(ctx); err != context.Canceled {
+		t.Errorf("got %v; expected %v", err, context.Canceled)
+	}
+}
+
+func TestParseRuntimeGroups(t *testing.T) {
+	g, err := ParseRuntimeGroups("gc, process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g, RuntimeGC|RuntimeProcess; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+
+	if _, err := ParseRuntimeGroups("gc,heap"); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
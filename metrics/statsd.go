@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagPolicy is what a StatsdMetricsReporter does with tags, which plain StatsD
+// doesn't support.
+type TagPolicy int
+
+const (
+	// DropTags ignores tags.
+	DropTags TagPolicy = iota
+
+	// FoldTags appends tags to the metric name, sorted by key, as
+	// .key_value segments. For instance, "requests" with the tag
+	// status:200 becomes "requests.status_200".
+	FoldTags
+)
+
+// StatsdOpts configures a StatsdMetricsReporter.
+type StatsdOpts struct {
+	// Prefix is prepended to every metric name, separated by a dot.
+	Prefix string
+
+	// Tags is what to do with tags.
+	Tags TagPolicy
+}
+
+// StatsdMetricsReporter is a MetricsReporter that sends metrics to a plain
+// StatsD server, over UDP, one metric per packet. Histogram, Distribution and
+// TimeInMilliseconds are sent as timers. Metrics with a rate below 1 are
+// sampled.
+type StatsdMetricsReporter struct {
+	opts StatsdOpts
+
+	mu   sync.Mutex
+	conn net.Conn
+	buf  []byte
+}
+
+// NewStatsdMetricsReporter returns a StatsdMetricsReporter that sends metrics
+// to the StatsD server at addr, a host:port.
+func NewStatsdMetricsReporter(addr string, opts StatsdOpts) (*StatsdMetricsReporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create statsd client: %v", err)
+	}
+	return &StatsdMetricsReporter{opts: opts, conn: conn}, nil
+}
+
+func (c *StatsdMetricsReporter) Count(name string, value int64, tags map[string]string, rate float64) error {
+	return c.send(name, strconv.FormatInt(value, 10), "c", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) Gauge(name string, value float64, tags map[string]string, rate float64) error {
+	return c.send(name, formatStatsdFloat(value), "g", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) Histogram(name string, value float64, tags map[string]string, rate float64) error {
+	return c.send(name, formatStatsdFloat(value), "ms", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) Distribution(name string, value float64, tags map[string]string, rate float64) error {
+	return c.send(name, formatStatsdFloat(value), "ms", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) Set(name string, value string, tags map[string]string, rate float64) error {
+	return c.send(name, sanitizeStatsd(value), "s", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) TimeInMilliseconds(name string, value float64, tags map[string]string, rate float64) error {
+	return c.send(name, formatStatsdFloat(value), "ms", tags, rate)
+}
+
+func (c *StatsdMetricsReporter) Close() error {
+	return c.conn.Close()
+}
+
+// send writes a name:value|type|@rate packet.
+func (c *StatsdMetricsReporter) send(name, value, typ string, tags map[string]string, rate float64) error {
+	if rate > 0 && rate < 1 && rand.Float64() > rate {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.buf[:0]
+	if c.opts.Prefix != "" {
+		b = append(b, sanitizeStatsd(c.opts.Prefix)...)
+		b = append(b, '.')
+	}
+	b = append(b, sanitizeStatsd(name)...)
+	if c.opts.Tags == FoldTags {
+		b = appendFoldedTags(b, tags)
+	}
+	b = append(b, ':')
+	b = append(b, value...)
+	b = append(b, '|')
+	b = append(b, typ...)
+	if rate > 0 && rate < 1 {
+		b = append(b, "|@"...)
+		b = strconv.AppendFloat(b, rate, 'g', -1, 64)
+	}
+	c.buf = b
+
+	_, err := c.conn.Write(b)
+	return err
+}
+
+func appendFoldedTags(b []byte, tags map[string]string) []byte {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b = append(b, '.')
+		b = append(b, sanitizeStatsdTag(k)...)
+		b = append(b, '_')
+		b = append(b, sanitizeStatsdTag(tags[k])...)
+	}
+	return b
+}
+
+// statsdReplacer replaces the characters that have a meaning in the StatsD
+// protocol.
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_", " ", "_")
+
+func sanitizeStatsd(s string) string {
+	return statsdReplacer.Replace(s)
+}
+
+// sanitizeStatsdTag also replaces dots, which would otherwise add levels to
+// the metric name.
+func sanitizeStatsdTag(s string) string {
+	return strings.Replace(sanitizeStatsd(s), ".", "_", -1)
+}
+
+func formatStatsdFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
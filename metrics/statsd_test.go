@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsdMetricsReporter(t *testing.T) {
+	tests := []struct {
+		opts StatsdOpts
+		send func(r *StatsdMetricsReporter) error
+		out  string
+	}{
+		{
+			StatsdOpts{},
+			func(r *StatsdMetricsReporter) error {
+				return r.Count("requests", 2, map[string]string{"status": "200"}, 1.0)
+			},
+			"requests:2|c",
+		},
+		{
+			StatsdOpts{Prefix: "app", Tags: FoldTags},
+			func(r *StatsdMetricsReporter) error {
+				return r.Count("requests", 1, map[string]string{"status": "200", "route": "GET /v1.0/users"}, 1.0)
+			},
+			"app.requests.route_GET_/v1_0/users.status_200:1|c",
+		},
+		{
+			StatsdOpts{},
+			func(r *StatsdMetricsReporter) error {
+				return r.TimeInMilliseconds("request.time", 1.5, nil, 1.0)
+			},
+			"request.time:1.5|ms",
+		},
+		{
+			StatsdOpts{},
+			func(r *StatsdMetricsReporter) error {
+				return r.Gauge("queue:depth", 3, nil, 1.0)
+			},
+			"queue_depth:3|g",
+		},
+		{
+			StatsdOpts{},
+			func(r *StatsdMetricsReporter) error {
+				return r.Set("users", "a|b", nil, 1.0)
+			},
+			"users:a_b|s",
+		},
+	}
+
+	for _, tt := range tests {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewStatsdMetricsReporter(conn.LocalAddr().String(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		must(t, tt.send(r))
+
+		buf := make([]byte, 512)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(buf[:n]), tt.out; got != want {
+			t.Errorf("got %s; expected %s", got, want)
+		}
+
+		r.Close()
+		conn.Close()
+	}
+}
+
+func TestNewMetricsReporterFromURL(t *testing.T) {
+	tests := []struct {
+		url string
+		typ interface{}
+	}{
+		{"statsd://127.0.0.1:8125?prefix=app&tags=fold", &StatsdMetricsReporter{}},
+		{"dogstatsd://127.0.0.1:8125?prefix=app", &DataDogMetricsReporter{}},
+		{"dogstatsd+unix:///var/run/datadog/dsd.socket", &DataDogMetricsReporter{}},
+		{"prometheus://?namespace=app", &PrometheusMetricsReporter{}},
+		{"noop://", &NoopMetricsReporter{}},
+		{"statsd://127.0.0.1:8125, noop://", MultiReporter{}},
+	}
+
+	for _, tt := range tests {
+		r, err := NewMetricsReporterFromURL(tt.url)
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if got, want := typeName(r), typeName(tt.typ); got != want {
+			t.Errorf("%s: got %s; expected %s", tt.url, got, want)
+		}
+		r.Close()
+	}
+
+	for _, url := range []string{"graphite://localhost", "statsd://localhost:8125?tags=keep"} {
+		if _, err := NewMetricsReporterFromURL(url); err == nil {
+			t.Errorf("%s: expected an error", url)
+		}
+	}
+}
+
+func typeName(v interface{}) string {
+	return fmt.Sprintf("%T", v)
+}
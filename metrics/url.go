@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/DataDog/datadog-go/statsd"
+)
+
+// NewMetricsReporterFromURL returns a MetricsReporter configured by a URL:
+//
+//	statsd://host:8125?prefix=app&tags=fold  plain StatsD, see StatsdOpts, tags=drop by default
+//	dogstatsd://host:8125?prefix=app         DogStatsD over UDP
+//	dogstatsd+unix:///var/run/dd.sock        DogStatsD over a Unix socket
+//	prometheus://?namespace=app              see PrometheusMetricsReporter
+//	noop://                                  no metrics
+//
+// Several comma separated URLs return a MultiReporter.
+func NewMetricsReporterFromURL(rawurl string) (MetricsReporter, error) {
+	if strings.Contains(rawurl, ",") {
+		return NewMultiReporterFromURLs(rawurl, NewMetricsReporterFromURL)
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", rawurl, err)
+	}
+	q := u.Query()
+
+	switch u.Scheme {
+	case "statsd":
+		opts := StatsdOpts{Prefix: q.Get("prefix")}
+		switch q.Get("tags") {
+		case "", "drop":
+			opts.Tags = DropTags
+		case "fold":
+			opts.Tags = FoldTags
+		default:
+			return nil, fmt.Errorf("unrecognized tags policy: %s", q.Get("tags"))
+		}
+		return NewStatsdMetricsReporter(u.Host, opts)
+	case "dogstatsd":
+		return newDataDogMetricsReporter(u.Host, q.Get("prefix"))
+	case "dogstatsd+unix":
+		return newDataDogMetricsReporter(statsd.UnixAddressPrefix+u.Path, q.Get("prefix"))
+	case "prometheus":
+		return NewPrometheusMetricsReporter(PrometheusOpts{Namespace: q.Get("namespace")}), nil
+	case "noop":
+		return &NoopMetricsReporter{}, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized metrics url scheme: %s", rawurl)
+}
+
+// NewMultiReporterFromURLs returns a MultiReporter of the MetricsReporters
+// returned by newReporter for each of the comma separated URLs of rawurls, or
+// the MetricsReporter of the URL when there's only one. The reporters are
+// closed if one of the URLs fails.
+func NewMultiReporterFromURLs(rawurls string, newReporter func(rawurl string) (MetricsReporter, error)) (MetricsReporter, error) {
+	if !strings.Contains(rawurls, ",") {
+		return newReporter(rawurls)
+	}
+
+	var r MultiReporter
+	for _, s := range strings.Split(rawurls, ",") {
+		m, err := newReporter(strings.TrimSpace(s))
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r = append(r, m)
+	}
+	return r, nil
+}
+
+// newDataDogMetricsReporter returns a DataDogMetricsReporter whose metric
+// names are prefixed with prefix and a dot, if set.
+func newDataDogMetricsReporter(addr, prefix string) (*DataDogMetricsReporter, error) {
+	var opts []statsd.Option
+	if prefix != "" {
+		opts = append(opts, statsd.WithNamespace(prefix+"."))
+	}
+	c, err := statsd.New(addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create statsd client: %v", err)
+	}
+	return &DataDogMetricsReporter{c}, nil
+}
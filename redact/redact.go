@@ -0,0 +1,318 @@
+// Package redact provides the policy used to remove sensitive values, like
+// passwords, tokens and email addresses, from logs, error reports and span
+// tags.
+//
+// Values are redacted when their key is sensitive, like "password" or
+// "X-Api-Key", or when they match one of the patterns of the policy, like a
+// bearer token in a message. The packages of this repository use Default,
+// which services can extend when they start:
+//
+//	redact.Default = redact.Default.WithKeys("ssn", "phone")
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces the sensitive values.
+const Mask = "[REDACTED]"
+
+// A Pattern matches sensitive values within strings.
+type Pattern struct {
+	Regexp *regexp.Regexp
+
+	// Valid, when set, tells whether a match of Regexp is sensitive, like
+	// a checksum.
+	Valid func(match string) bool
+
+	// Candidate, when set, tells whether a string may contain a match, so
+	// that Regexp only runs on those that might.
+	Candidate func(s string) bool
+}
+
+var (
+	// BearerToken matches bearer tokens, like in an Authorization header.
+	BearerToken = Pattern{
+		Regexp:    regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		Candidate: func(s string) bool { return containsFold(s, "bearer") },
+	}
+
+	// JWT matches JSON web tokens.
+	JWT = Pattern{
+		Regexp:    regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		Candidate: func(s string) bool { return strings.Contains(s, "eyJ") },
+	}
+
+	// Email matches email addresses.
+	Email = Pattern{
+		Regexp:    regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Candidate: func(s string) bool { return strings.IndexByte(s, '@') >= 0 },
+	}
+
+	// CardNumber matches card numbers of 15 or 16 digits, optionally
+	// grouped with spaces or dashes, that pass the Luhn check.
+	CardNumber = Pattern{
+		Regexp:    regexp.MustCompile(`\b\d(?:[ -]?\d){14,15}\b`),
+		Valid:     luhn,
+		Candidate: func(s string) bool { return countDigits(s) >= 15 },
+	}
+)
+
+// DefaultKeys are the sensitive keys of Default.
+var DefaultKeys = []string{
+	"authorization",
+	"cookie",
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api_key",
+	"apikey",
+	"private_key",
+}
+
+// DefaultPatterns are the patterns of Default.
+var DefaultPatterns = []Pattern{BearerToken, JWT, Email, CardNumber}
+
+// Default is the policy used by the logger, httpx/errors, the tracing
+// contrib packages and service_client. It should be set before it's used,
+// when the program starts. Set it to nil to disable redaction.
+var Default = New(DefaultKeys, DefaultPatterns...)
+
+// Policy redacts sensitive values. The methods of a nil Policy don't redact
+// anything.
+type Policy struct {
+	keys     []string
+	patterns []Pattern
+}
+
+// New returns a Policy that redacts the values of the given keys, and the
+// substrings of values matching the patterns.
+//
+// Keys are matched ignoring case, with dashes matching underscores, and
+// match the keys ending with them after an underscore or a dot: "token"
+// matches "X-Auth-Token", "access_token" and "user.token".
+func New(keys []string, patterns ...Pattern) *Policy {
+	p := &Policy{}
+	return p.WithKeys(keys...).WithPatterns(patterns...)
+}
+
+// WithKeys returns a copy of p that also redacts the given keys.
+func (p *Policy) WithKeys(keys ...string) *Policy {
+	c := p.clone()
+	for _, k := range keys {
+		if k = normalizeKey(k); k != "" {
+			c.keys = append(c.keys, k)
+		}
+	}
+	return c
+}
+
+// WithPatterns returns a copy of p that also redacts the given patterns.
+func (p *Policy) WithPatterns(patterns ...Pattern) *Policy {
+	c := p.clone()
+	c.patterns = append(c.patterns, patterns...)
+	return c
+}
+
+func (p *Policy) clone() *Policy {
+	if p == nil {
+		return &Policy{}
+	}
+	return &Policy{
+		keys:     append([]string{}, p.keys...),
+		patterns: append([]Pattern{}, p.patterns...),
+	}
+}
+
+// IsSensitive reports whether the values of key are redacted.
+func (p *Policy) IsSensitive(key string) bool {
+	if p == nil || len(p.keys) == 0 {
+		return false
+	}
+	key = normalizeKey(key)
+	for _, k := range p.keys {
+		if key == k {
+			return true
+		}
+		if n := len(key) - len(k); n > 0 && strings.HasSuffix(key, k) && (key[n-1] == '_' || key[n-1] == '.') {
+			return true
+		}
+	}
+	return false
+}
+
+// Scrub returns s with the substrings matching the patterns of p replaced by
+// Mask, as well as the values of "key=value" pairs with a sensitive key, like
+// in a query string. It implements the service_client.Scrubber interface.
+func (p *Policy) Scrub(s string) string {
+	if p == nil {
+		return s
+	}
+	if len(p.keys) > 0 && strings.IndexByte(s, '=') >= 0 {
+		s = p.scrubPairs(s)
+	}
+	for _, pat := range p.patterns {
+		if pat.Candidate != nil && !pat.Candidate(s) {
+			continue
+		}
+		s = pat.Regexp.ReplaceAllStringFunc(s, func(m string) string {
+			if pat.Valid != nil && !pat.Valid(m) {
+				return m
+			}
+			return Mask
+		})
+	}
+	return s
+}
+
+var pairRegexp = regexp.MustCompile(`([A-Za-z0-9_.\-]+)=([^\s&;,"']+)`)
+
+// scrubPairs masks the values of the key=value pairs in s with a sensitive
+// key.
+func (p *Policy) scrubPairs(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range pairRegexp.FindAllStringSubmatchIndex(s, -1) {
+		if !p.IsSensitive(s[m[2]:m[3]]) {
+			continue
+		}
+		b.WriteString(s[last:m[4]])
+		b.WriteString(Mask)
+		last = m[5]
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Value returns Mask when key is sensitive. Otherwise, strings, errors and
+// fmt.Stringers are scrubbed, see Scrub, and returned as strings when
+// anything was redacted. Other values are returned as is.
+func (p *Policy) Value(key string, v interface{}) interface{} {
+	if r, ok := p.value(key, v); ok {
+		return r
+	}
+	return v
+}
+
+// value returns the redacted v, and whether anything was redacted.
+func (p *Policy) value(key string, v interface{}) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	if p.IsSensitive(key) {
+		return Mask, true
+	}
+
+	var (
+		s  string
+		ok = true
+	)
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s, ok = methodString(v.Error)
+	case fmt.Stringer:
+		s, ok = methodString(v.String)
+	default:
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+	if r := p.Scrub(s); r != s {
+		return r, true
+	}
+	return "", false
+}
+
+// Pairs returns the key value pairs with their values redacted, see Value.
+// The pairs are returned as is when nothing is redacted, otherwise they're
+// copied.
+func (p *Policy) Pairs(pairs []interface{}) []interface{} {
+	if p == nil {
+		return pairs
+	}
+	var redacted []interface{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			key = fmt.Sprint(pairs[i])
+		}
+		v, ok := p.value(key, pairs[i+1])
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]interface{}{}, pairs...)
+		}
+		redacted[i+1] = v
+	}
+	if redacted == nil {
+		return pairs
+	}
+	return redacted
+}
+
+// methodString returns the result of fn, the Error or String method of a
+// value. ok is false when it panics, like the methods of some nil pointers, so
+// that the value is left to fmt, which handles it.
+func methodString(fn func() string) (s string, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			s, ok = "", false
+		}
+	}()
+	return fn(), true
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.Replace(key, "-", "_", -1))
+}
+
+// containsFold reports whether s contains substr, an ASCII lower case string,
+// ignoring case.
+func containsFold(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func countDigits(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if '0' <= s[i] && s[i] <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
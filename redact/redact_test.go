@@ -0,0 +1,115 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPolicy_IsSensitive(t *testing.T) {
+	p := New([]string{"password", "api_key"})
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"Password", true},
+		{"db_password", true},
+		{"user.password", true},
+		{"X-Api-Key", true},
+		{"API_KEY", true},
+		{"passwords", false},
+		{"password_hint", false},
+		{"mypassword", false},
+		{"request_id", false},
+	}
+
+	for _, tt := range tests {
+		if got, want := p.IsSensitive(tt.key), tt.want; got != want {
+			t.Errorf("IsSensitive(%q) => %v; want %v", tt.key, got, want)
+		}
+	}
+}
+
+func TestPolicy_Scrub(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"nothing to see", "nothing to see"},
+		{"Authorization: Bearer abc.def-123", "Authorization: [REDACTED]"},
+		{"jwt eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_n-4", "jwt [REDACTED]"},
+		{"user jane.doe+test@example.com signed up", "user [REDACTED] signed up"},
+		{"card 4242 4242 4242 4242 declined", "card [REDACTED] declined"},
+		{"card 4242-4242-4242-4241 declined", "card 4242-4242-4242-4241 declined"},
+		{"trace 1234567890123456789", "trace 1234567890123456789"},
+		{"/users?id=1&access_token=s3cr3t&page=2", "/users?id=1&access_token=[REDACTED]&page=2"},
+		{"password=hunter2 user=jane", "password=[REDACTED] user=jane"},
+	}
+
+	for _, tt := range tests {
+		if got, want := Default.Scrub(tt.in), tt.out; got != want {
+			t.Errorf("Scrub(%q) => %q; want %q", tt.in, got, want)
+		}
+	}
+}
+
+func TestPolicy_Pairs(t *testing.T) {
+	m := map[string]int{"a": 1}
+	pairs := []interface{}{"user", "jane@example.com", "token", 42, "err", errors.New("password=hunter2"), "count", 1, "m", m}
+
+	got := Default.Pairs(pairs)
+	want := []interface{}{"user", Mask, "token", Mask, "err", "password=[REDACTED]", "count", 1, "m", m}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pairs => %v; want %v", got, want)
+	}
+	if pairs[1] != "jane@example.com" {
+		t.Fatal("expected Pairs to copy the pairs it redacts")
+	}
+
+	safe := []interface{}{"count", 1, "m", m}
+	if got := Default.Pairs(safe); &got[0] != &safe[0] {
+		t.Fatal("expected Pairs to return the pairs as is when nothing is redacted")
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+
+	if p.IsSensitive("password") {
+		t.Fatal("expected a nil Policy not to redact anything")
+	}
+	if got, want := p.Scrub("password=hunter2"), "password=hunter2"; got != want {
+		t.Fatalf("Scrub => %q; want %q", got, want)
+	}
+	if got, want := p.WithKeys("ssn").Value("ssn", "123"), Mask; got != want {
+		t.Fatalf("Value => %q; want %q", got, want)
+	}
+}
+
+func TestPolicy_WithKeys(t *testing.T) {
+	p := Default.WithKeys("ssn")
+
+	if !p.IsSensitive("user_ssn") {
+		t.Fatal("expected the added key to be sensitive")
+	}
+	if Default.IsSensitive("ssn") {
+		t.Fatal("expected WithKeys not to modify the Policy")
+	}
+}
+
+func TestPolicy_TypedNil(t *testing.T) {
+	var u *url.URL
+	var err *url.Error
+
+	pairs := []interface{}{"url", u, "err", err}
+	got := Default.Pairs(pairs)
+	if &got[0] != &pairs[0] {
+		t.Fatal("expected typed nil values to be returned as is")
+	}
+	if got, want := fmt.Sprintf("%v %v", got[1], got[3]), "<nil> <nil>"; got != want {
+		t.Fatalf("Pairs => %q; want %q", got, want)
+	}
+}
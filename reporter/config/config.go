@@ -10,8 +10,9 @@ import (
 )
 
 // Returns a MultiReporter from URL strings such as:
-// "hb://api.honeybadger.io/?key=hbkey&environment=hbenv" or
-// "rollbar://api.rollbar.com/?key=rollbarkey&environment=rollbarenv"
+// "hb://api.honeybadger.io/?key=hbkey&environment=hbenv",
+// "rollbar://api.rollbar.com/?key=rollbarkey&environment=rollbarenv" or
+// "log://". Other schemes can be added with Register.
 func NewReporterFromUrls(urls []string) (reporter.Reporter, error) {
 	multiRep := reporter.MultiReporter{}
 	for _, url := range urls {
@@ -24,23 +25,41 @@ func NewReporterFromUrls(urls []string) (reporter.Reporter, error) {
 	return multiRep, nil
 }
 
-func newReporterFromUrl(u string) (reporter.Reporter, error) {
-	parsedURL, err := url.Parse(u)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't parse %s: %#v", u, err)
-	}
-	switch parsedURL.Scheme {
-	case "hb":
-		q := parsedURL.Query()
+// Factory builds a reporter.Reporter from a URL.
+type Factory func(u *url.URL) (reporter.Reporter, error)
+
+var factories = map[string]Factory{
+	"hb": func(u *url.URL) (reporter.Reporter, error) {
+		q := u.Query()
 		return hb2.NewReporter(hb2.Config{
 			ApiKey:      q.Get("key"),
 			Environment: q.Get("environment"),
 		}), nil
-	case "rollbar":
-		q := parsedURL.Query()
+	},
+	"rollbar": func(u *url.URL) (reporter.Reporter, error) {
+		q := u.Query()
 		rollbar.ConfigureReporter(q.Get("key"), q.Get("environment"))
 		return rollbar.Reporter, nil
-	default:
+	},
+	"log": func(u *url.URL) (reporter.Reporter, error) {
+		return reporter.NewLogReporter(), nil
+	},
+}
+
+// Register makes a reporter available under the given URL scheme. Registering
+// an existing scheme replaces it.
+func Register(scheme string, f Factory) {
+	factories[scheme] = f
+}
+
+func newReporterFromUrl(u string) (reporter.Reporter, error) {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %#v", u, err)
+	}
+	f, ok := factories[parsedURL.Scheme]
+	if !ok {
 		return nil, fmt.Errorf("unrecognized reporter url scheme: %s", u)
 	}
+	return f(parsedURL)
 }
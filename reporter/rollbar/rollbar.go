@@ -38,6 +38,12 @@ func ConfigureReporter(token, environment string) {
 	rollbar.SetEnvironment(environment)
 }
 
+// SetEndpoint sets the Rollbar endpoint, like
+// https://api.rollbar.com/api/1/item/.
+func SetEndpoint(endpoint string) {
+	rollbar.SetEndpoint(endpoint)
+}
+
 func ConfigureFromEnvironment() {
 	if token := os.Getenv(EnvAccessToken); token != "" {
 		rollbar.SetToken(token)
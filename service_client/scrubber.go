@@ -1,13 +1,17 @@
 package service_client
 
-import ()
+import (
+	"github.com/remind101/pkg/redact"
+)
 
 // A Scrubber will process a string and remove PII making it safe for
-// logging and metrics.
+// logging and metrics. A *redact.Policy is a Scrubber.
 type Scrubber interface {
 	Scrub(string) string
 }
 
+var _ Scrubber = (*redact.Policy)(nil)
+
 type NoopScrubber struct{}
 
 func (s *NoopScrubber) Scrub(str string) string {
@@ -14,6 +14,8 @@ import (
 	httpsignatures "github.com/99designs/httpsignatures-go"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/redact"
+	"github.com/remind101/pkg/tracing/tracecontext"
 )
 
 var AggressiveTransport = &http.Transport{
@@ -47,7 +49,10 @@ type ServiceClientOpts struct {
 	IncludeForwardedHeaders []string
 	SigningKeyId            string
 	SigningKey              string
-	Scrubber                Scrubber
+
+	// Scrubber removes PII from the uri span tag. The default is
+	// redact.Default. Use a NoopScrubber to disable it.
+	Scrubber Scrubber
 }
 
 func NewServiceClient(serviceURL string) *serviceClient {
@@ -63,7 +68,7 @@ func NewServiceClientWithOpts(serviceURL string, opts ServiceClientOpts) *servic
 	client := httpx.NewServiceClient(serviceURL, httpClient)
 	signer := httpsignatures.DefaultSha256Signer
 	if opts.Scrubber == nil {
-		opts.Scrubber = &NoopScrubber{}
+		opts.Scrubber = redact.Default
 	}
 
 	return &serviceClient{
@@ -142,6 +147,7 @@ func (c *serviceClient) trace(ctx context.Context, req *http.Request) func() {
 		span.Context(),
 		opentracing.HTTPHeaders,
 		opentracing.HTTPHeadersCarrier(req.Header))
+	tracecontext.Inject(ctx, req.Header)
 	span.SetTag("uri", c.logScrubber.Scrub(req.URL.String()))
 	return func() {
 		span.Finish()
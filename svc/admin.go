@@ -0,0 +1,279 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/httpx/middleware"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
+)
+
+// AdminOpts configures the admin server.
+type AdminOpts struct {
+	// Addr is the address to listen on, either "host:port", or
+	// "unix:/path/to/socket" for a unix socket.
+	Addr string
+
+	// BasicAuth, as "user:pass", protects every endpoint when set.
+	BasicAuth string
+
+	// Health is served on LivenessPath and ReadinessPath. The zero value
+	// uses DefaultHealth.
+	Health *Health
+
+	// LogLevel is served, and can be changed, on /loglevel. The zero value
+	// uses LogLevel.
+	LogLevel *logger.LevelVar
+
+	// LogLevels, the level rules of the named loggers, are served and can
+	// be changed on /loglevel too. The zero value uses
+	// logger.DefaultLevels.
+	LogLevels *logger.Levels
+
+	// Metrics is served on /metrics. The zero value serves metrics.Reporter
+	// when it's an http.Handler, like a metrics.PrometheusMetricsReporter,
+	// or a metrics.MultiReporter with one, and a JSON snapshot of
+	// metrics.RuntimeStats otherwise.
+	Metrics http.Handler
+}
+
+// NewAdminHandler returns an http.Handler that serves:
+//
+//	/debug/pprof/  net/http/pprof profiles
+//	/buildinfo     the build info of the binary, from debug.ReadBuildInfo
+//	/healthz       the liveness report
+//	/readyz        the readiness report
+//	/loglevel      the log levels, which a PUT changes
+//	/metrics       the metrics, see AdminOpts.Metrics
+//
+// It's meant to be served on a separate port from the application, see
+// AdminHook.
+func NewAdminHandler(opts AdminOpts) http.Handler {
+	health := opts.Health
+	if health == nil {
+		health = DefaultHealth
+	}
+	level := opts.LogLevel
+	if level == nil {
+		level = LogLevel
+	}
+	levels := opts.LogLevels
+	if levels == nil {
+		levels = logger.DefaultLevels
+	}
+
+	r := httpx.NewRouter()
+
+	r.Handle("/debug/pprof/cmdline", httpHandler(pprof.Cmdline))
+	r.Handle("/debug/pprof/profile", httpHandler(pprof.Profile))
+	r.Handle("/debug/pprof/symbol", httpHandler(pprof.Symbol))
+	r.Handle("/debug/pprof/trace", httpHandler(pprof.Trace))
+	r.Match(func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/debug/pprof/")
+	}, httpHandler(pprof.Index))
+
+	r.HandleFunc("/buildinfo", serveBuildInfo).Methods("GET")
+	r.Handle(LivenessPath, health.LivenessHandler()).Methods("GET")
+	r.Handle(ReadinessPath, health.ReadinessHandler()).Methods("GET")
+	r.Handle("/loglevel", &logLevelHandler{level, levels}).Methods("GET", "PUT")
+
+	if opts.Metrics != nil {
+		r.Handle(MetricsPath, httpHandler(opts.Metrics.ServeHTTP)).Methods("GET")
+	} else {
+		r.HandleFunc(MetricsPath, serveMetrics).Methods("GET")
+	}
+
+	h := httpx.Handler(r)
+	if opts.BasicAuth != "" {
+		user, pass, _ := strings.Cut(opts.BasicAuth, ":")
+		h = middleware.BasicAuth(h, user, pass, "admin")
+	}
+
+	return middleware.BackgroundContext(h)
+}
+
+// AdminHook returns a Hook that serves NewAdminHandler(opts) on opts.Addr while
+// the Lifecycle runs.
+//
+//	env.Lifecycle.Append(svc.AdminHook(svc.AdminOpts{Addr: "localhost:6060"}))
+func AdminHook(opts AdminOpts) Hook {
+	srv := &http.Server{Handler: NewAdminHandler(opts)}
+
+	return Hook{
+		Name: "admin server",
+		Start: func(ctx context.Context) error {
+			ln, err := listenAdmin(opts.Addr)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := srv.Serve(ln); err != http.ErrServerClosed {
+					logAndReport(ctx, err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	}
+}
+
+// listenAdmin listens on a tcp address, or on a unix socket when addr starts
+// with "unix:".
+func listenAdmin(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// Remove the socket left behind by a previous process.
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// httpHandler adapts an http.HandlerFunc to an httpx.Handler.
+func httpHandler(f http.HandlerFunc) httpx.Handler {
+	return httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		f(w, r.WithContext(ctx))
+		return nil
+	})
+}
+
+func serveBuildInfo(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "build info not available", http.StatusNotFound)
+		return nil
+	}
+	return writeJSON(w, http.StatusOK, info)
+}
+
+func serveMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if h := metricsHandler(metrics.Reporter); h != nil {
+		h.ServeHTTP(w, r)
+		return nil
+	}
+	return writeJSON(w, http.StatusOK, metrics.RuntimeStats())
+}
+
+// metricsHandler returns the http.Handler serving the metrics of r, like a
+// PrometheusMetricsReporter, if any.
+func metricsHandler(r metrics.MetricsReporter) http.Handler {
+	switch r := r.(type) {
+	case http.Handler:
+		return r
+	case interface{ Handler() http.Handler }:
+		return r.Handler()
+	}
+	return nil
+}
+
+// logLevelHandler serves the current log level and the level rules of the
+// named loggers, and changes them on PUT. They can be sent as JSON,
+// {"level":"debug","levels":{"client.*":"debug"}}, where an empty level
+// removes a rule, or as plain text, "debug" or "client.*=debug".
+type logLevelHandler struct {
+	level  *logger.LevelVar
+	levels *logger.Levels
+}
+
+type logLevel struct {
+	Level  string            `json:"level,omitempty"`
+	Levels map[string]string `json:"levels,omitempty"`
+}
+
+func (h *logLevelHandler) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "PUT" {
+		b, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+		if err != nil {
+			return err
+		}
+
+		var req logLevel
+		if err := json.Unmarshal(b, &req); err != nil {
+			req = parseLogLevel(string(b))
+		}
+
+		if err := h.set(ctx, req); err != nil {
+			return writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	resp := logLevel{Level: logger.FormatLevel(h.level.Level())}
+	for _, rule := range h.levels.Rules() {
+		if resp.Levels == nil {
+			resp.Levels = make(map[string]string)
+		}
+		resp.Levels[rule.Pattern] = logger.FormatLevel(rule.Level)
+	}
+	return writeJSON(w, http.StatusOK, resp)
+}
+
+// set validates and applies the levels of req.
+func (h *logLevelHandler) set(ctx context.Context, req logLevel) error {
+	if req.Level == "" && len(req.Levels) == 0 {
+		return errInvalidLogLevel
+	}
+
+	lvl, ok := logger.LookupLevel(req.Level)
+	if req.Level != "" && !ok {
+		return errInvalidLogLevel
+	}
+	var rules logger.LevelRules
+	for pattern, s := range req.Levels {
+		if s == "" {
+			continue
+		}
+		rule, err := logger.ParseLevelRules(pattern + "=" + s)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule...)
+	}
+
+	for _, rule := range rules {
+		h.levels.Set(rule.Pattern, rule.Level)
+		logger.Info(ctx, "Log level changed", "pattern", rule.Pattern, "level", logger.FormatLevel(rule.Level))
+	}
+	for pattern, s := range req.Levels {
+		if s == "" {
+			h.levels.Unset(pattern)
+			logger.Info(ctx, "Log level removed", "pattern", pattern)
+		}
+	}
+	if req.Level != "" {
+		h.level.Set(lvl)
+		logger.Info(ctx, "Log level changed", "level", logger.FormatLevel(lvl))
+	}
+	return nil
+}
+
+var errInvalidLogLevel = errors.New("invalid log level")
+
+// parseLogLevel parses a plain text request, either a level, or a
+// pattern=level rule.
+func parseLogLevel(s string) logLevel {
+	s = strings.TrimSpace(s)
+	if pattern, lvl, ok := strings.Cut(s, "="); ok {
+		return logLevel{Levels: map[string]string{strings.TrimSpace(pattern): strings.TrimSpace(lvl)}}
+	}
+	return logLevel{Level: s}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,151 @@
+package svc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/svc"
+)
+
+func TestAdminHandler(t *testing.T) {
+	level := logger.NewLevelVar(logger.INFO)
+	h := svc.NewAdminHandler(svc.AdminOpts{
+		BasicAuth: "admin:secret",
+		Health:    svc.NewHealth(),
+		LogLevel:  level,
+	})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth("admin", "secret")
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		return resp
+	}
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		contains           string
+	}{
+		{"GET", "/debug/pprof/", "", 200, "goroutine"},
+		{"GET", "/debug/pprof/goroutine?debug=1", "", 200, "goroutine profile"},
+		{"GET", "/buildinfo", "", 200, `"GoVersion"`},
+		{"GET", "/healthz", "", 200, `"status":"ok"`},
+		{"GET", "/readyz", "", 200, `"status":"ok"`},
+		{"GET", "/metrics", "", 200, `"goroutine"`},
+		{"GET", "/loglevel", "", 200, `{"level":"info"}`},
+		{"PUT", "/loglevel", `{"level":"debug"}`, 200, `{"level":"debug"}`},
+		{"PUT", "/loglevel", "warn", 200, `{"level":"warn"}`},
+		{"PUT", "/loglevel", "verbose", 400, `invalid log level`},
+	}
+
+	for _, tt := range tests {
+		resp := do(tt.method, tt.path, tt.body)
+		if got, want := resp.Code, tt.code; got != want {
+			t.Errorf("%s %s: got %d; expected %d", tt.method, tt.path, got, want)
+		}
+		if !strings.Contains(resp.Body.String(), tt.contains) {
+			t.Errorf("%s %s: got %s; expected it to contain %s", tt.method, tt.path, resp.Body.String(), tt.contains)
+		}
+	}
+
+	if got, want := level.Level(), logger.WARN; got != want {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/buildinfo", nil))
+	if got, want := resp.Code, http.StatusUnauthorized; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+}
+
+func TestAdminHandler_LogLevels(t *testing.T) {
+	levels := logger.NewLevels()
+	h := svc.NewAdminHandler(svc.AdminOpts{
+		LogLevel:  logger.NewLevelVar(logger.INFO),
+		LogLevels: levels,
+	})
+
+	tests := []struct {
+		body     string
+		code     int
+		contains string
+	}{
+		{`{"levels":{"client.*":"debug"}}`, 200, `{"level":"info","levels":{"client.*":"debug"}}`},
+		{"client.redis=warn", 200, `"client.redis":"warn"`},
+		{`{"level":"error","levels":{"client.*":""}}`, 200, `{"level":"error","levels":{"client.redis":"warn"}}`},
+		{`{"levels":{"client.*":"verbose"}}`, 400, `invalid log level`},
+		{`{"levels":{"[":"debug"}}`, 400, `invalid log level pattern`},
+		{"", 400, `invalid log level`},
+	}
+
+	for _, tt := range tests {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("PUT", "/loglevel", strings.NewReader(tt.body)))
+		if got, want := resp.Code, tt.code; got != want {
+			t.Errorf("PUT %s: got %d; expected %d", tt.body, got, want)
+		}
+		if !strings.Contains(resp.Body.String(), tt.contains) {
+			t.Errorf("PUT %s: got %s; expected it to contain %s", tt.body, resp.Body.String(), tt.contains)
+		}
+	}
+
+	if got, _ := levels.Level("client.redis.pool"); got != logger.WARN {
+		t.Errorf("got %v; expected %v", got, logger.WARN)
+	}
+}
+
+func TestAdminHandler_Prometheus(t *testing.T) {
+	orig := metrics.Reporter
+	defer func() { metrics.Reporter = orig }()
+
+	for _, multi := range []bool{false, true} {
+		metrics.Reporter = metrics.NewPrometheusMetricsReporter(metrics.PrometheusOpts{})
+		if multi {
+			metrics.Reporter = metrics.MultiReporter{&metrics.NoopMetricsReporter{}, metrics.Reporter}
+		}
+		metrics.Count("jobs", 1, nil, 1.0)
+
+		resp := httptest.NewRecorder()
+		svc.NewAdminHandler(svc.AdminOpts{}).ServeHTTP(resp, httptest.NewRequest("GET", svc.MetricsPath, nil))
+
+		if !strings.Contains(resp.Body.String(), "\njobs_total 1\n") {
+			t.Errorf("got %s; expected the prometheus exposition", resp.Body.String())
+		}
+	}
+}
+
+func TestAdminHook(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "admin.sock")
+	hook := svc.AdminHook(svc.AdminOpts{Addr: "unix:" + sock})
+
+	ctx := context.Background()
+	if err := hook.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Stop(ctx)
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	resp, err := c.Get("http://admin/loglevel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got, want := resp.StatusCode, 200; got != want {
+		t.Errorf("got %d; expected %d", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package svc
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/reporter/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// TracerFactory installs a global tracer configured from a URL, and returns a
+// function that flushes and stops it.
+type TracerFactory func(u *url.URL, c Config) (func(), error)
+
+// MetricsFactory builds a metrics.MetricsReporter from a URL.
+type MetricsFactory func(u *url.URL, c Config) (metrics.MetricsReporter, error)
+
+var (
+	tracerFactories = map[string]TracerFactory{
+		"datadog": func(u *url.URL, c Config) (func(), error) {
+			return initDatadogTracer(c, u.Host), nil
+		},
+		"otlp": func(u *url.URL, c Config) (func(), error) {
+			return initOtlpTracer(u, c, true)
+		},
+		"otlps": func(u *url.URL, c Config) (func(), error) {
+			return initOtlpTracer(u, c, false)
+		},
+		"otlp+grpc": func(u *url.URL, c Config) (func(), error) {
+			return initOtelGRPCTracer(c, otlpGRPCOptions(u, true)...)
+		},
+		"otlps+grpc": func(u *url.URL, c Config) (func(), error) {
+			return initOtelGRPCTracer(c, otlpGRPCOptions(u, false)...)
+		},
+		"noop": func(u *url.URL, c Config) (func(), error) {
+			return func() {}, nil
+		},
+	}
+
+	metricsFactories = map[string]MetricsFactory{
+		"datadog": func(u *url.URL, c Config) (metrics.MetricsReporter, error) {
+			return metrics.NewDataDogMetricsReporter(u.Host)
+		},
+		"statsd":         metricsFromURL,
+		"dogstatsd":      metricsFromURL,
+		"dogstatsd+unix": metricsFromURL,
+		"prometheus":     metricsFromURL,
+		"noop":           metricsFromURL,
+	}
+)
+
+// metricsFromURL is the MetricsFactory of the schemes supported by
+// metrics.NewMetricsReporterFromURL.
+func metricsFromURL(u *url.URL, c Config) (metrics.MetricsReporter, error) {
+	return metrics.NewMetricsReporterFromURL(u.String())
+}
+
+// RegisterTracer makes a tracing backend available to InitAll under the given
+// URL scheme, for TRACING_URL. Built in schemes are:
+//
+//	datadog://localhost:8126    the Datadog opentracer
+//	otlp://localhost:4317       OpenTelemetry, exported with OTLP
+//	otlps://collector:4317      same, with TLS
+//	otlp+grpc://localhost:4317  OpenTelemetry, exported with OTLP over gRPC
+//	otlps+grpc://collector:4317 same, with TLS
+//	noop://                     no tracing
+//
+// otlp:// and otlps:// export over gRPC when the port is 4317, the port of
+// OTLP over gRPC, and over http otherwise, like on 4318.
+//
+// The otlp schemes install an OpenTelemetry tracer, which NewStandardHandler
+// and the clients of the client package then use by default, see
+// Env.OpenTelemetry. Other tracers are used through opentracing, unless
+// HandlerOpts.OpenTelemetry or client.OtelTracing are set.
+
+func RegisterTracer(scheme string, f TracerFactory) {
+	tracerFactories[scheme] = f
+}
+
+// RegisterMetrics makes a metrics backend available to InitAll under the given
+// URL scheme, for METRICS_URL. Built in schemes are:
+//
+//	datadog://localhost:8125               Datadog statsd
+//	dogstatsd://localhost:8125?prefix=app  same, with an optional prefix
+//	dogstatsd+unix:///var/run/dd.sock      same, over a Unix socket
+//	statsd://localhost:8125?tags=fold      plain StatsD
+//	prometheus://?namespace=app            Prometheus, served by the admin server
+//	noop://                                no metrics
+//
+// See metrics.NewMetricsReporterFromURL for their parameters. METRICS_URL can
+// hold several comma separated URLs, to send metrics to all of them.
+func RegisterMetrics(scheme string, f MetricsFactory) {
+	metricsFactories[scheme] = f
+}
+
+// RegisterReporter makes an error reporter available to InitAll under the
+// given URL scheme, for REPORTER_URLS. See reporter/config.Register.
+func RegisterReporter(scheme string, f config.Factory) {
+	config.Register(scheme, f)
+}
+
+func newTracerFromURL(rawurl string, c Config) (func(), error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", rawurl, err)
+	}
+	f, ok := tracerFactories[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized tracing url scheme: %s", rawurl)
+	}
+	return f(u, c)
+}
+
+func newMetricsFromURL(rawurl string, c Config) (metrics.MetricsReporter, error) {
+	return metrics.NewMultiReporterFromURLs(rawurl, func(rawurl string) (metrics.MetricsReporter, error) {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %v", rawurl, err)
+		}
+		f, ok := metricsFactories[u.Scheme]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized metrics url scheme: %s", rawurl)
+		}
+		return f(u, c)
+	})
+}
+
+// otlpGRPCPort is the default port of OTLP over gRPC.
+const otlpGRPCPort = "4317"
+
+// initOtlpTracer installs an OpenTelemetry tracer for an otlp:// or otlps://
+// URL, that exports over gRPC when the port is otlpGRPCPort, and over http
+// otherwise.
+func initOtlpTracer(u *url.URL, c Config, insecure bool) (func(), error) {
+	if u.Port() == otlpGRPCPort {
+		return initOtelGRPCTracer(c, otlpGRPCOptions(u, insecure)...)
+	}
+	return initOtelTracer(c, otlpOptions(u, insecure)...)
+}
+
+// otlpOptions returns the http exporter options for an otlp:// or otlps://
+// URL.
+func otlpOptions(u *url.URL, insecure bool) []otlptracehttp.Option {
+	var opts []otlptracehttp.Option
+	if u.Host != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return opts
+}
+
+// otlpGRPCOptions returns the exporter options for an otlp+grpc:// or
+// otlps+grpc:// URL.
+func otlpGRPCOptions(u *url.URL, insecure bool) []otlptracegrpc.Option {
+	var opts []otlptracegrpc.Option
+	if u.Host != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(u.Host))
+	}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return opts
+}
@@ -0,0 +1,117 @@
+package svc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/svc"
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+type testMetricsReporter struct {
+	metrics.NoopMetricsReporter
+	url *url.URL
+}
+
+func TestInitAllWithConfig_URLs(t *testing.T) {
+	orig := metrics.Reporter
+	defer func() { metrics.Reporter = orig }()
+
+	svc.RegisterMetrics("test", func(u *url.URL, c svc.Config) (metrics.MetricsReporter, error) {
+		return &testMetricsReporter{url: u}, nil
+	})
+
+	env := svc.InitAllWithConfig(svc.Config{
+		TracingURL:   "noop://",
+		MetricsURL:   "test://localhost:1234",
+		ReporterURLs: []string{"log://"},
+	})
+	defer env.Close()
+
+	r, ok := metrics.Reporter.(*testMetricsReporter)
+	if !ok {
+		t.Fatalf("got %T; expected the registered metrics reporter", metrics.Reporter)
+	}
+	if got, want := r.url.Host, "localhost:1234"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+
+	if got, want := len(env.Reporter.(reporter.MultiReporter)), 1; got != want {
+		t.Errorf("got %d reporters; expected %d", got, want)
+	}
+	if env.Health != env.Lifecycle.Health {
+		t.Error("expected the Health flipped by the Lifecycle")
+	}
+}
+
+// traceCollector is an OTLP over gRPC collector that records the names of the
+// spans it receives.
+type traceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	spans chan string
+}
+
+func (c *traceCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.spans <- span.Name
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestInitAllWithConfig_OTLPGRPCPort(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:4317")
+	if err != nil {
+		t.Skipf("the OTLP over gRPC port isn't available: %v", err)
+	}
+	c := &traceCollector{spans: make(chan string, 1)}
+	s := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(s, c)
+	go s.Serve(l)
+	defer s.Stop()
+
+	orig := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(orig)
+
+	env := svc.InitAllWithConfig(svc.Config{
+		TracingURL:   "otlp://localhost:4317",
+		MetricsURL:   "noop://",
+		ReporterURLs: []string{"log://"},
+	})
+	if !env.OpenTelemetry {
+		t.Error("expected an OpenTelemetry tracer to be selected")
+	}
+
+	// The standard handler traces with the selected tracer by default.
+	r := httpx.NewRouter()
+	r.HandleFunc("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	h := svc.NewStandardHandler(svc.HandlerOpts{
+		Router:   r,
+		Reporter: env.Reporter,
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	env.Close()
+
+	select {
+	case name := <-c.spans:
+		if got, want := name, "server.request"; got != want {
+			t.Errorf("got %s; expected %s", got, want)
+		}
+	default:
+		t.Fatal("expected the span to be exported over gRPC")
+	}
+}
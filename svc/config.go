@@ -0,0 +1,95 @@
+package svc
+
+import (
+	"fmt"
+
+	"github.com/remind101/pkg/config"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
+)
+
+// Config holds the settings used by InitAll, read from the environment with
+// LoadConfig.
+type Config struct {
+	// AppName, Process and Release identify the service, to name traces and
+	// tag metrics.
+	AppName string `env:"EMPIRE_APPNAME"`
+	Process string `env:"EMPIRE_PROCESS"`
+	Release string `env:"EMPIRE_RELEASE"`
+
+	// TracingURL selects the tracing backend by scheme, see
+	// RegisterTracer. When empty, the Datadog tracer is used with
+	// DDTraceAddr.
+	TracingURL string `env:"TRACING_URL"`
+
+	// DDTraceAddr is the host:port of the local trace agent server.
+	DDTraceAddr string `env:"DDTRACE_ADDR"`
+
+	// MetricsURL selects the metrics backend by scheme, see
+	// RegisterMetrics. When empty, Datadog statsd is used with StatsdAddr.
+	MetricsURL string `env:"METRICS_URL"`
+
+	// StatsdAddr is the host:port of the statsd server. Metrics are
+	// discarded when it and MetricsURL are empty.
+	StatsdAddr string `env:"STATSD_ADDR"`
+
+	// RuntimeMetrics are the groups of runtime metrics reported, see
+	// metrics.ParseRuntimeGroups. Add the memstats group to keep the
+	// metrics reported by metrics.Runtime before, goroutine and
+	// runtime.MemStats.*, which stop the world to be read.
+	RuntimeMetrics metrics.RuntimeGroup `env:"RUNTIME_METRICS" default:"gc,memory,scheduler,sync"`
+
+	// ReporterURLs selects the error reporters, see
+	// reporter/config.NewReporterFromUrls. When empty, errors are logged
+	// and reported to Rollbar if it's configured.
+	ReporterURLs []string `env:"REPORTER_URLS" secret:"true"`
+
+	// LogLevel is the level of the logger.
+	LogLevel string `env:"LOG_LEVEL" default:"error"`
+
+	// LogLevels are the levels of the named loggers, like
+	// "client.*=debug,db=warn", see logger.Named.
+	LogLevels logger.LevelRules `env:"LOG_LEVELS"`
+
+	// LogFormat is the format of the logger: text, json or logfmt.
+	LogFormat logger.Format `env:"LOG_FORMAT" default:"text"`
+
+	// RedactKeys are the keys whose values are redacted from logs, error
+	// reports and span tags, in addition to redact.DefaultKeys.
+	RedactKeys []string `env:"REDACT_KEYS"`
+
+	// Rollbar settings. The Rollbar reporter is only used when both the
+	// access token and the environment are set.
+	RollbarAccessToken string `env:"ROLLBAR_ACCESS_TOKEN" secret:"true"`
+	RollbarEnvironment string `env:"ROLLBAR_ENVIRONMENT"`
+	RollbarEndpoint    string `env:"ROLLBAR_ENDPOINT"`
+}
+
+// LoadConfig reads a Config from the environment. See config.Load for the
+// available options, like reading .env files.
+func LoadConfig(opts ...config.Option) (Config, error) {
+	var c Config
+	err := config.Load(&c, opts...)
+	return c, err
+}
+
+// ServiceName returns the name of the service, used to name traces.
+func (c Config) ServiceName() string {
+	return fmt.Sprintf("%s.%s", c.AppName, c.Process)
+}
+
+// metricsTags returns the default tags of metrics.
+func (c Config) metricsTags() map[string]string {
+	return map[string]string{
+		"empire.app.name":    c.AppName,
+		"empire.app.process": c.Process,
+		"empire.app.release": c.Release,
+	}
+}
+
+// envConfig reads a Config from the environment, for the Init functions that
+// don't take one. Fields that fail to parse are left to their zero value.
+func envConfig() Config {
+	c, _ := LoadConfig()
+	return c
+}
@@ -0,0 +1,92 @@
+package svc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/remind101/pkg/config"
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/svc"
+)
+
+func TestLoadConfig(t *testing.T) {
+	env := map[string]string{
+		"EMPIRE_APPNAME":       "api",
+		"EMPIRE_PROCESS":       "web",
+		"ROLLBAR_ACCESS_TOKEN": "token",
+	}
+	c, err := svc.LoadConfig(config.WithLookup(func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := c.ServiceName(), "api.web"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if got, want := c.LogLevel, "error"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if got, want := c.LogFormat, logger.FormatText; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if got, want := c.RuntimeMetrics, metrics.RuntimeDefault; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if dump := config.Dump(c); !strings.Contains(dump, "ROLLBAR_ACCESS_TOKEN=****") {
+		t.Errorf("expected access token to be masked, got:\n%s", dump)
+	}
+}
+
+func TestLoadConfig_LogFormat(t *testing.T) {
+	lookup := func(format string) config.Option {
+		return config.WithLookup(func(k string) (string, bool) {
+			return format, k == "LOG_FORMAT"
+		})
+	}
+
+	c, err := svc.LoadConfig(lookup("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.LogFormat, logger.FormatJSON; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+
+	if _, err := svc.LoadConfig(lookup("xml")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestLoadConfig_RedactKeys(t *testing.T) {
+	c, err := svc.LoadConfig(config.WithLookup(func(k string) (string, bool) {
+		return "ssn,phone", k == "REDACT_KEYS"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(c.RedactKeys, ","), "ssn,phone"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+}
+
+func TestLoadConfig_LogLevels(t *testing.T) {
+	c, err := svc.LoadConfig(config.WithLookup(func(k string) (string, bool) {
+		return "client.*=debug,db=warn", k == "LOG_LEVELS"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(c.LogLevels), 2; got != want {
+		t.Fatalf("got %d; expected %d", got, want)
+	}
+	if got, want := c.LogLevels[0], (logger.LevelRule{Pattern: "client.*", Level: logger.DEBUG}); got != want {
+		t.Errorf("got %v; expected %v", got, want)
+	}
+	if dump := config.Dump(c); !strings.Contains(dump, "LOG_LEVELS=client.*=debug,db=warn") {
+		t.Errorf("expected the rules to be dumped, got:\n%s", dump)
+	}
+}
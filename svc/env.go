@@ -3,14 +3,25 @@ package svc
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/remind101/pkg/client/request"
 	"github.com/remind101/pkg/logger"
 	"github.com/remind101/pkg/metrics"
+	"github.com/remind101/pkg/redact"
 	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/reporter/config"
 	"github.com/remind101/pkg/reporter/rollbar"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
@@ -22,34 +33,105 @@ type Env struct {
 	Logger   logger.Logger
 	Context  context.Context
 	Close    func() // Should be called in a defer in main().
+
+	// Lifecycle stops the tracer and metrics after the server, when used
+	// with Env.RunServer. Append hooks to it for the application's own
+	// components.
+	Lifecycle *Lifecycle
+
+	// Health is the registry that the Lifecycle flips to failing readiness
+	// when it drains the server. Serve it with HandlerOpts.Health or
+	// AdminOpts.Health.
+	Health *Health
+
+	// OpenTelemetry reports whether the tracer selected by TracingURL is an
+	// OpenTelemetry one. NewStandardHandler and the clients of the client
+	// package then trace with OpenTelemetry instead of opentracing.
+	OpenTelemetry bool
+}
+
+// RunServer runs srv with the Lifecycle, logging and reporting errors with the
+// logger and reporter of the Env. See Lifecycle.RunServer.
+func (e Env) RunServer(srv *http.Server) error {
+	return e.Lifecycle.RunServer(e.Context, srv)
 }
 
 // InitAll will initialize all the common dependencies such as metrics, reporting,
-// tracing, and logging.
+// tracing, and logging, from a Config read from the environment.
 func InitAll() Env {
-	traceCloser := InitTracer()
-	metricsCloser := InitMetrics()
+	c, err := LoadConfig()
+	if err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+	}
+	return InitAllWithConfig(c)
+}
+
+// InitAllWithConfig is like InitAll, with the given Config.
+func InitAllWithConfig(c Config) Env {
+	initRedaction(c)
 
-	l := InitLogger()
+	traceCloser := initTracer(c)
+	metricsCloser := initMetrics(c)
+
+	l := initLogger(c)
 	logger.DefaultLogger = l
 
-	r := InitReporter()
+	r := initReporter(c)
 
 	ctx := reporter.WithReporter(context.Background(), r)
 	ctx = logger.WithLogger(ctx, l)
 
-	go func() {
-		defer reporter.Monitor(ctx)
-		metrics.Runtime()
-	}()
+	lc := NewLifecycle()
+	lc.Append(Hook{Name: "tracer", Stop: func(context.Context) error {
+		traceCloser()
+		return nil
+	}})
+	lc.Append(Hook{Name: "metrics", Stop: func(context.Context) error {
+		metricsCloser()
+		return nil
+	}})
+	lc.Append(runtimeMetricsHook(ctx, c))
+	if err := lc.Start(ctx); err != nil {
+		fmt.Printf("Could not start the lifecycle hooks: %v\n", err)
+		logAndReport(ctx, err)
+	}
 
 	return Env{
 		Logger:   l,
 		Reporter: r,
 		Context:  ctx,
 		Close: func() {
-			traceCloser()
-			metricsCloser()
+			lc.Stop(ctx)
+		},
+		Lifecycle:     lc,
+		Health:        lc.Health,
+		OpenTelemetry: otelTracing,
+	}
+}
+
+// runtimeMetricsHook returns a Hook that reports the runtime metrics of
+// c.RuntimeMetrics until it's stopped.
+func runtimeMetricsHook(ctx context.Context, c Config) Hook {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	collector := metrics.NewRuntimeCollector(metrics.RuntimeCollectorOpts{
+		Groups: c.RuntimeMetrics,
+	})
+
+	return Hook{
+		Name: "runtime metrics",
+		Start: func(context.Context) error {
+			go func() {
+				defer close(done)
+				defer reporter.Monitor(ctx)
+				collector.Run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
 		},
 	}
 }
@@ -57,17 +139,32 @@ func InitAll() Env {
 // InitTracer configures a global datadog tracer.
 //
 // Env Vars:
+// * TRACING_URL - Selects another tracing backend by URL scheme, see RegisterTracer.
 // * DDTRACE_ADDR - The host:port of the local trace agent server.
 // * EMPIRE_APPNAME - App name, used to construct the service name.
 // * EMPIRE_PROCESS - Process name, used to construct the service name.
 func InitTracer() func() {
+	return initTracer(envConfig())
+}
+
+func initTracer(c Config) func() {
+	setOtelTracing(false)
+	if c.TracingURL != "" {
+		closer, err := newTracerFromURL(c.TracingURL, c)
+		if err != nil {
+			fmt.Printf("Could not initialize tracer, skipping tracing: %v\n", err)
+			return func() {}
+		}
+		return closer
+	}
+	return initDatadogTracer(c, c.DDTraceAddr)
+}
+
+func initDatadogTracer(c Config, addr string) func() {
 	var opts []tracer.StartOption
 	// create a Tracer configuration
-	opts = append(opts, tracer.WithService(fmt.Sprintf(
-		"%s.%s",
-		os.Getenv("EMPIRE_APPNAME"),
-		os.Getenv("EMPIRE_PROCESS"))))
-	if addr := os.Getenv("DDTRACE_ADDR"); addr != "" {
+	opts = append(opts, tracer.WithService(c.ServiceName()))
+	if addr != "" {
 		opts = append(opts, tracer.WithAgentAddr(addr))
 	}
 
@@ -83,13 +180,101 @@ func InitTracer() func() {
 	}
 }
 
+// InitOtelTracer configures a global OpenTelemetry TracerProvider that exports
+// spans with OTLP over http, and a W3C trace context and baggage propagator.
+// Use it instead of InitTracer. NewStandardHandler and the clients of the
+// client package then trace with OpenTelemetry by default.
+//
+// Env Vars:
+// * OTEL_EXPORTER_OTLP_ENDPOINT - The base URL of the OTLP collector.
+// * EMPIRE_APPNAME - App name, used to construct the service name.
+// * EMPIRE_PROCESS - Process name, used to construct the service name.
+func InitOtelTracer() func() {
+	closer, err := initOtelTracer(envConfig())
+	if err != nil {
+		fmt.Printf("Could not create OTLP exporter, skipping OpenTelemetry tracer: %v\n", err)
+		return func() {}
+	}
+	return closer
+}
+
+func initOtelTracer(c Config, opts ...otlptracehttp.Option) (func(), error) {
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return installOtelTracer(c, exporter), nil
+}
+
+// initOtelGRPCTracer is like initOtelTracer, with OTLP over gRPC.
+func initOtelGRPCTracer(c Config, opts ...otlptracegrpc.Option) (func(), error) {
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return installOtelTracer(c, exporter), nil
+}
+
+// installOtelTracer sets the global TracerProvider, exporting spans with
+// exporter, and returns a function that flushes and stops it.
+func installOtelTracer(c Config, exporter sdktrace.SpanExporter) func() {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(c.ServiceName()),
+		)),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	setOtelTracing(true)
+
+	return func() {
+		setOtelTracing(false)
+
+		// Flush any buffered spans.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			fmt.Printf("OpenTelemetry tracer shutdown: %v\n", err)
+		}
+	}
+}
+
+// otelTracing is whether the installed tracer is an OpenTelemetry one, so
+// that NewStandardHandler traces with OpenTelemetry by default.
+var otelTracing bool
+
+// setOtelTracing sets otelTracing, and the default tracing of the clients of
+// the client package.
+func setOtelTracing(enabled bool) {
+	otelTracing = enabled
+	request.DefaultOtelTracing = enabled
+}
+
 // InitMetrics configures pkg/metrics
 //
 // Env Vars:
+// * METRICS_URL - Selects the metrics backend by URL scheme, see RegisterMetrics.
 // * STATSD_ADDR - The host:port of the statsd server.
 func InitMetrics() func() {
-	if addr := os.Getenv("STATSD_ADDR"); addr != "" {
-		metrics.SetEmpireDefaultTags()
+	return initMetrics(envConfig())
+}
+
+func initMetrics(c Config) func() {
+	if c.MetricsURL != "" {
+		r, err := newMetricsFromURL(c.MetricsURL, c)
+		if err != nil {
+			fmt.Printf("Could not initialize metrics, skipping metrics: %v\n", err)
+		} else {
+			metrics.SetDefaultTags(c.metricsTags())
+			metrics.Reporter = r
+		}
+	} else if addr := c.StatsdAddr; addr != "" {
+		metrics.SetDefaultTags(c.metricsTags())
 		metrics.Reporter, _ = metrics.NewDataDogMetricsReporter(addr)
 	}
 
@@ -98,37 +283,76 @@ func InitMetrics() func() {
 	}
 }
 
+// LogLevel is the level of the logger returned by InitLogger. It can be
+// changed at runtime, for instance through the admin server.
+var LogLevel = logger.NewLevelVar(logger.ERROR)
+
+// initRedaction adds c.RedactKeys to redact.Default.
+func initRedaction(c Config) {
+	if len(c.RedactKeys) > 0 {
+		keys := append(append([]string{}, redact.DefaultKeys...), c.RedactKeys...)
+		redact.Default = redact.New(keys, redact.DefaultPatterns...)
+	}
+}
+
 // InitLogger configures a leveled logger.
 //
 // Env Vars:
 // * LOG_LEVEL - The log level
+// * LOG_LEVELS - The levels of the named loggers, like "client.*=debug"
+// * LOG_FORMAT - The log format: text, json or logfmt
 //
 // If you want to replace the global default logger:
 //	logger.DefaultLogger = InitLogger()
 func InitLogger() logger.Logger {
+	return initLogger(envConfig())
+}
+
+func initLogger(c Config) logger.Logger {
 	lvl := logger.ERROR
-	if ll := os.Getenv("LOG_LEVEL"); ll != "" {
+	if ll := c.LogLevel; ll != "" {
 		lvl = logger.ParseLevel(ll)
 	}
+	LogLevel.Set(lvl)
+	if len(c.LogLevels) > 0 {
+		logger.DefaultLevels.Replace(c.LogLevels)
+	}
 
-	return logger.New(log.New(os.Stdout, "", 0), lvl)
+	return logger.NewWithFormat(os.Stdout, c.LogFormat, LogLevel)
 }
 
 // InitReporter configures and returns a reporter.Reporter instance.
 //
 // Env Vars:
+// * REPORTER_URLS - Comma separated reporter URLs, which replace the defaults, see RegisterReporter.
 // * ROLLBAR_ACCESS_TOKEN - The Rollbar access token
 // * ROLLBAR_ENVIRONMENT  - The Rollbar environment (staging, production)
 // * ROLLBAR_ENDPOINT     - The Rollbar endpoint: https://api.rollbar.com/api/1/item/
 func InitReporter() reporter.Reporter {
+	return initReporter(envConfig())
+}
+
+func initReporter(c Config) reporter.Reporter {
+	if len(c.ReporterURLs) > 0 {
+		rep, err := config.NewReporterFromUrls(c.ReporterURLs)
+		if err == nil {
+			return rep
+		}
+		fmt.Printf("Could not initialize reporters, using the log reporter: %v\n", err)
+		return reporter.NewLogReporter()
+	}
+
 	rep := reporter.MultiReporter{}
 
 	// Log Reporter, uses package level logger.
 	rep = append(rep, reporter.NewLogReporter())
 
 	// Rollbar reporter
-	if os.Getenv(rollbar.EnvAccessToken) != "" && os.Getenv(rollbar.EnvEnvironment) != "" {
-		rollbar.ConfigureFromEnvironment()
+	if c.RollbarAccessToken != "" && c.RollbarEnvironment != "" {
+		rollbar.ConfigureReporter(c.RollbarAccessToken, c.RollbarEnvironment)
+		if c.RollbarEndpoint != "" {
+			rollbar.SetEndpoint(c.RollbarEndpoint)
+		}
 		rep = append(rep, rollbar.Reporter)
 	} else {
 		fmt.Println("Rollbar is not configured, skipping Rollbar reporter")
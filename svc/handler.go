@@ -13,6 +13,7 @@
 //		h := svc.NewStandardHandler(svc.HandlerOpts{
 //			Router:   r,
 //			Reporter: env.Reporter,
+//			Health:   env.Health,
 //	})
 //
 // 	s := svc.NewServer(h, svc.WithPort("8080"))
@@ -37,6 +38,36 @@ type HandlerOpts struct {
 	BasicAuth         string
 	ErrorHandler      middleware.ErrorHandlerFunc
 	HandlerTimeout    time.Duration
+
+	// StreamingTimeout enforces HandlerTimeout, and timeouts set on routes
+	// with httpx.Route.Timeout, without buffering responses. See
+	// middleware.StreamingTimeoutHandler.
+	StreamingTimeout bool
+
+	// OpenTelemetry traces requests with OpenTelemetry instead of
+	// opentracing. It's the default when InitAll or InitOtelTracer
+	// installed an OpenTelemetry tracer, see Env.OpenTelemetry.
+	OpenTelemetry bool
+
+	// BaggageHeaders maps W3C baggage keys to header names that the
+	// baggage values should be made available as. See
+	// middleware.TraceContext.
+	BaggageHeaders map[string]string
+
+	// AccessLog, when set, replaces the default request logging with a
+	// middleware.AccessLogger. Router defaults to the handler's Router.
+	AccessLog *middleware.AccessLogOpts
+
+	// Health, when set, serves its liveness and readiness reports on
+	// LivenessPath and ReadinessPath, bypassing the middleware stack. It
+	// must be the Health of the Lifecycle running the server, like
+	// Env.Health or DefaultHealth, for readiness to fail while the server
+	// drains: other registries aren't flipped.
+	Health *Health
+
+	// Metrics, when set, is served on MetricsPath, bypassing the middleware
+	// stack, like a metrics.PrometheusMetricsReporter.
+	Metrics http.Handler
 }
 
 // NewStandardHandler returns an http.Handler with a standard middleware stack.
@@ -46,10 +77,11 @@ type HandlerOpts struct {
 func NewStandardHandler(opts HandlerOpts) http.Handler {
 	h := httpx.Handler(opts.Router)
 
-	if opts.HandlerTimeout != 0 {
-		// Timeout requests after the given Timeout duration.
-		h = middleware.TimeoutHandler(h, opts.HandlerTimeout)
-	}
+	// Timeout requests after the route's timeout, if set, or the given
+	// HandlerTimeout duration.
+	rt := middleware.RouteTimeouts(h, opts.Router, opts.HandlerTimeout)
+	rt.Streaming = opts.StreamingTimeout
+	h = rt
 
 	// Recover from panics. A panic is converted to an error. This should be first,
 	// even though it means panics in middleware will not be recovered, because
@@ -66,10 +98,29 @@ func NewStandardHandler(opts HandlerOpts) http.Handler {
 
 	// Add request tracing. Must go after the HandleError middleware in order
 	// to capture the status code written to the response.
-	h = middleware.OpentracingTracing(h, opts.Router)
+	if opts.OpenTelemetry || otelTracing {
+		h = middleware.OtelTracing(h, opts.Router)
+	} else {
+		h = middleware.OpentracingTracing(h, opts.Router)
+	}
 
-	// Insert logger into context and log requests at INFO level.
-	h = middleware.LogTo(h, middleware.LoggerWithRequestID)
+	// Extract W3C trace context and baggage, so it's propagated by http
+	// clients. Must go after the logger is inserted, so baggage items can be
+	// added as log fields.
+	tc := middleware.ExtractTraceContext(h)
+	tc.BaggageHeaders = opts.BaggageHeaders
+	h = tc
+
+	// Insert logger into context and log requests.
+	if opts.AccessLog != nil {
+		alOpts := *opts.AccessLog
+		if alOpts.Router == nil {
+			alOpts.Router = opts.Router
+		}
+		h = middleware.InsertLogger(middleware.AccessLog(h, alOpts), middleware.LoggerWithRequestID)
+	} else {
+		h = middleware.LogTo(h, middleware.LoggerWithRequestID)
+	}
 
 	// Add reporter to context and request to reporter context.
 	h = middleware.WithReporter(h, opts.Reporter)
@@ -94,5 +145,22 @@ func NewStandardHandler(opts HandlerOpts) http.Handler {
 
 	// Wrap the route in middleware to add a context.Context. This middleware must be
 	// last as it acts as the adaptor between http.Handler and httpx.Handler.
-	return middleware.BackgroundContext(h)
+	hh := middleware.BackgroundContext(h)
+
+	// Serve health checks and metrics without logging, tracing or
+	// authenticating probes and scrapes.
+	if opts.Health == nil && opts.Metrics == nil {
+		return hh
+	}
+
+	mux := http.NewServeMux()
+	if opts.Health != nil {
+		mux.Handle(LivenessPath, opts.Health.LivenessHandler())
+		mux.Handle(ReadinessPath, opts.Health.ReadinessHandler())
+	}
+	if opts.Metrics != nil {
+		mux.Handle(MetricsPath, opts.Metrics)
+	}
+	mux.Handle("/", hh)
+	return mux
 }
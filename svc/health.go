@@ -0,0 +1,306 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Paths that the handler returned by NewStandardHandler serves health checks
+// on when HandlerOpts.Health is set, and metrics on when HandlerOpts.Metrics
+// is set.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	MetricsPath   = "/metrics"
+)
+
+// Health check statuses.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// DefaultCheckTimeout is the timeout for checks that don't set one.
+var DefaultCheckTimeout = 2 * time.Second
+
+// DefaultHealth is the Health registry used by RegisterCheck, and flipped to
+// failing readiness by RunServer when shutdown begins.
+var DefaultHealth = NewHealth()
+
+// Check is a named health check for a component, like a database pool, a
+// redis connection, a downstream service or a background worker.
+type Check struct {
+	// Name identifies the check in reports.
+	Name string
+
+	// Func returns an error when the component is unhealthy. The context is
+	// canceled once Timeout has elapsed.
+	Func func(ctx context.Context) error
+
+	// Timeout for a single run of Func. The zero value uses
+	// DefaultCheckTimeout.
+	Timeout time.Duration
+
+	// Critical checks fail readiness when they fail. Failures of other
+	// checks only mark the service as degraded.
+	Critical bool
+
+	// Liveness checks are also run for liveness probes. Only use this for
+	// checks whose failure means the process needs to be restarted.
+	Liveness bool
+}
+
+// CheckResult is the result of running a Check.
+type CheckResult struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Critical  bool      `json:"critical"`
+	Duration  float64   `json:"duration_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HealthReport is the aggregated result of running checks.
+type HealthReport struct {
+	Status       string                 `json:"status"`
+	ShuttingDown bool                   `json:"shutting_down,omitempty"`
+	Checks       map[string]CheckResult `json:"checks"`
+}
+
+// Health is a registry of health checks.
+type Health struct {
+	// CacheTTL is how long check results are reused for, so that frequent
+	// probes from several sources don't overload the components being
+	// checked. Zero disables caching, but concurrent probes still share a
+	// single run of each check.
+	CacheTTL time.Duration
+
+	mu           sync.Mutex
+	checks       []*cachedCheck
+	shuttingDown bool
+}
+
+// NewHealth returns a new Health registry that caches results for a second.
+func NewHealth() *Health {
+	return &Health{CacheTTL: time.Second}
+}
+
+// RegisterCheck registers a check with DefaultHealth.
+func RegisterCheck(c Check) {
+	DefaultHealth.Register(c)
+}
+
+// Register adds a check to the registry. Registering a check with the same
+// name as an existing one replaces it.
+func (h *Health) Register(c Check) {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, cc := range h.checks {
+		if cc.Name == c.Name {
+			h.checks[i] = &cachedCheck{Check: c}
+			return
+		}
+	}
+	h.checks = append(h.checks, &cachedCheck{Check: c})
+}
+
+// ShutDown marks the service as shutting down, which fails readiness so that
+// load balancers stop sending it new requests.
+func (h *Health) ShutDown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shuttingDown = true
+}
+
+// Liveness runs the liveness checks.
+func (h *Health) Liveness(ctx context.Context) HealthReport {
+	return h.run(ctx, true)
+}
+
+// Readiness runs all the checks. The report fails when any critical check
+// fails or the service is shutting down.
+func (h *Health) Readiness(ctx context.Context) HealthReport {
+	return h.run(ctx, false)
+}
+
+func (h *Health) run(ctx context.Context, liveness bool) HealthReport {
+	h.mu.Lock()
+	checks := make([]*cachedCheck, 0, len(h.checks))
+	for _, c := range h.checks {
+		if !liveness || c.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	ttl := h.CacheTTL
+	report := HealthReport{
+		Status:       StatusOK,
+		ShuttingDown: h.shuttingDown && !liveness,
+		Checks:       make(map[string]CheckResult, len(checks)),
+	}
+	h.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *cachedCheck) {
+			defer wg.Done()
+			results[i] = c.result(ctx, ttl)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		r := results[i]
+		report.Checks[c.Name] = r
+		if r.Status != StatusFail {
+			continue
+		}
+		if r.Critical || liveness {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	if report.ShuttingDown {
+		report.Status = StatusFail
+	}
+
+	return report
+}
+
+// LivenessHandler returns a handler that serves the liveness report as JSON.
+func (h *Health) LivenessHandler() *HealthHandler {
+	return &HealthHandler{report: h.Liveness}
+}
+
+// ReadinessHandler returns a handler that serves the readiness report as JSON.
+func (h *Health) ReadinessHandler() *HealthHandler {
+	return &HealthHandler{report: h.Readiness}
+}
+
+// HealthHandler serves a HealthReport as JSON. It responds with a 503 when the
+// report fails, and a 200 otherwise. It implements both http.Handler and
+// httpx.Handler.
+type HealthHandler struct {
+	report func(context.Context) HealthReport
+}
+
+// ServeHTTPContext implements the httpx.Handler interface.
+func (h *HealthHandler) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	report := h.report(ctx)
+
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(report)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.ServeHTTPContext(r.Context(), w, r)
+}
+
+// cachedCheck wraps a Check to cache its last result, and to share a single
+// run between concurrent callers.
+type cachedCheck struct {
+	Check
+
+	mu       sync.Mutex
+	last     CheckResult
+	inflight chan struct{}
+}
+
+func (c *cachedCheck) result(ctx context.Context, ttl time.Duration) CheckResult {
+	c.mu.Lock()
+	if !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < ttl {
+		defer c.mu.Unlock()
+		return c.last
+	}
+
+	ch := c.inflight
+	if ch == nil {
+		ch = make(chan struct{})
+		c.inflight = ch
+		go c.run()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return CheckResult{
+			Status:    StatusFail,
+			Error:     ctx.Err().Error(),
+			Critical:  c.Critical,
+			CheckedAt: time.Now(),
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// run runs the check in the background, so that callers giving up early don't
+// cancel it for others.
+func (c *cachedCheck) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runFunc(ctx, c.Func)
+
+	r := CheckResult{
+		Status:    StatusOK,
+		Critical:  c.Critical,
+		Duration:  float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		r.Status = StatusFail
+		r.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.last = r
+	close(c.inflight)
+	c.inflight = nil
+	c.mu.Unlock()
+}
+
+// runFunc runs fn, returning when it does or when ctx is done, whichever
+// comes first. Panics are converted to errors.
+func runFunc(ctx context.Context, fn func(context.Context) error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				errCh <- fmt.Errorf("panic: %v", v)
+			}
+		}()
+		errCh <- fn(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/svc"
+)
+
+func TestHealth(t *testing.T) {
+	h := svc.NewHealth()
+	h.Register(svc.Check{
+		Name:     "db",
+		Func:     func(ctx context.Context) error { return nil },
+		Critical: true,
+		Liveness: true,
+	})
+	h.Register(svc.Check{
+		Name: "cache",
+		Func: func(ctx context.Context) error { return errors.New("connection refused") },
+	})
+
+	ctx := context.Background()
+
+	r := h.Readiness(ctx)
+	if got, want := r.Status, svc.StatusDegraded; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if got, want := r.Checks["cache"].Error, "connection refused"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+
+	r = h.Liveness(ctx)
+	if got, want := r.Status, svc.StatusOK; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if _, ok := r.Checks["cache"]; ok {
+		t.Error("expected liveness to only run liveness checks")
+	}
+
+	h.Register(svc.Check{
+		Name:     "db",
+		Func:     func(ctx context.Context) error { <-ctx.Done(); return nil },
+		Timeout:  10 * time.Millisecond,
+		Critical: true,
+	})
+	h.CacheTTL = 0
+
+	r = h.Readiness(ctx)
+	if got, want := r.Status, svc.StatusFail; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if got, want := r.Checks["db"].Error, context.DeadlineExceeded.Error(); got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+}
+
+func TestHealth_Cache(t *testing.T) {
+	var calls int32
+	h := svc.NewHealth()
+	h.Register(svc.Check{
+		Name: "slow",
+		Func: func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Readiness(context.Background())
+		}()
+	}
+	wg.Wait()
+	h.Readiness(context.Background())
+
+	if got, want := atomic.LoadInt32(&calls), int32(1); got != want {
+		t.Errorf("got %d calls; expected %d", got, want)
+	}
+}
+
+func TestStandardHandler_Health(t *testing.T) {
+	health := svc.NewHealth()
+	health.Register(svc.Check{
+		Name: "db",
+		Func: func(ctx context.Context) error { return nil },
+	})
+
+	h := svc.NewStandardHandler(svc.HandlerOpts{
+		Router:    httpx.NewRouter(),
+		Health:    health,
+		BasicAuth: "user:pass",
+	})
+
+	get := func(path string) (int, svc.HealthReport) {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		h.ServeHTTP(resp, req)
+
+		var r svc.HealthReport
+		json.NewDecoder(resp.Body).Decode(&r)
+		return resp.Code, r
+	}
+
+	if code, r := get(svc.ReadinessPath); code != 200 || r.Checks["db"].Status != svc.StatusOK {
+		t.Errorf("got %d %+v; expected a passing report", code, r)
+	}
+
+	health.ShutDown()
+
+	if code, r := get(svc.ReadinessPath); code != 503 || !r.ShuttingDown {
+		t.Errorf("got %d %+v; expected a failing report", code, r)
+	}
+	if code, _ := get(svc.LivenessPath); code != 200 {
+		t.Errorf("got %d; expected liveness to pass while shutting down", code)
+	}
+}
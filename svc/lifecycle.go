@@ -0,0 +1,294 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/remind101/pkg/logger"
+	"github.com/remind101/pkg/reporter"
+)
+
+// DefaultStopTimeout is the deadline for hooks that don't set a StopTimeout.
+var DefaultStopTimeout = 5 * time.Second
+
+// Hook is a component of the service that needs to be started before the
+// server accepts requests, and stopped after it stopped serving them, like a
+// background worker or a connection pool.
+type Hook struct {
+	// Name identifies the hook in logs, errors and DependsOn.
+	Name string
+
+	// Start is called when the service starts. Optional.
+	Start func(ctx context.Context) error
+
+	// Stop is called when the service stops, with a context that is
+	// canceled after StopTimeout. Optional.
+	Stop func(ctx context.Context) error
+
+	// DependsOn lists the hooks that must be started before, and stopped
+	// after, this one.
+	DependsOn []string
+
+	// StopTimeout is the deadline for Stop. The zero value uses
+	// DefaultStopTimeout.
+	StopTimeout time.Duration
+}
+
+// Lifecycle starts and stops hooks in order, and runs an http.Server between
+// the two. Hooks are started in the order they're appended, unless DependsOn
+// requires otherwise, and stopped in reverse order.
+type Lifecycle struct {
+	// Health is flipped to failing readiness when shutdown begins. Nil
+	// disables it.
+	Health *Health
+
+	// DrainDelay is how long to wait, after readiness starts failing, before
+	// the server stops accepting connections. This gives load balancers time
+	// to notice and stop sending new requests.
+	DrainDelay time.Duration
+
+	// ShutdownTimeout is the deadline for in flight requests to complete once
+	// the server stops accepting connections.
+	ShutdownTimeout time.Duration
+
+	mu        sync.Mutex
+	hooks     []Hook
+	started   []Hook
+	isStarted map[int]bool
+}
+
+// NewLifecycle returns a Lifecycle that flips DefaultHealth when shutting down
+// and gives in flight requests 5 seconds to complete.
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		Health:          DefaultHealth,
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// Append adds a hook to the lifecycle.
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// Start calls the Start function of each hook that isn't started yet, in
+// dependency order. If a hook fails to start, the hooks that were already
+// started are stopped and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	order, err := sortHooks(hooks)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, i := range order {
+		l.mu.Lock()
+		started := l.isStarted[i]
+		l.mu.Unlock()
+		if started {
+			continue
+		}
+
+		h := hooks[i]
+		if h.Start != nil {
+			if err := runFunc(ctx, h.Start); err != nil {
+				err = fmt.Errorf("svc: starting %s: %v", h.Name, err)
+				l.Stop(ctx)
+				return err
+			}
+		}
+
+		l.mu.Lock()
+		if l.isStarted == nil {
+			l.isStarted = make(map[int]bool)
+		}
+		l.isStarted[i] = true
+		l.started = append(l.started, h)
+		l.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Stop calls the Stop function of each started hook, in reverse order, each
+// with its own deadline. Errors are logged and reported to the reporter in
+// ctx, if any, and returned as a *reporter.MultiError. Calling Stop more than
+// once is safe, hooks are only stopped once.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	started := l.started
+	l.started = nil
+	l.isStarted = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		h := started[i]
+		if h.Stop == nil {
+			continue
+		}
+
+		timeout := h.StopTimeout
+		if timeout == 0 {
+			timeout = DefaultStopTimeout
+		}
+
+		stopCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := runFunc(stopCtx, h.Stop)
+		cancel()
+
+		if err != nil {
+			err = fmt.Errorf("svc: stopping %s: %v", h.Name, err)
+			logAndReport(ctx, err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &reporter.MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// RunServer starts the hooks, then serves requests with srv until ctx is
+// canceled or the process receives SIGINT or SIGTERM. It then shuts down in
+// order: readiness starts failing, the server stops accepting connections
+// after DrainDelay, in flight requests are given ShutdownTimeout to complete,
+// and the hooks are stopped.
+//
+// The returned error holds every error that occurred, starting and stopping.
+func (l *Lifecycle) RunServer(ctx context.Context, srv *http.Server) error {
+	if err := l.Start(ctx); err != nil {
+		logAndReport(ctx, err)
+		return err
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		// Printed, so that it's visible whatever the level of the logger.
+		fmt.Printf("HTTP server listening on address: \"%s\"\n", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	var errs []error
+
+	select {
+	case sig := <-sigCh:
+		fmt.Println("Received signal, stopping.", "signal", sig)
+		l.drain(ctx, srv, &errs)
+	case <-ctx.Done():
+		l.drain(ctx, srv, &errs)
+	case err := <-serveErr:
+		// Error starting the listener.
+		err = fmt.Errorf("svc: HTTP server ListenAndServe: %v", err)
+		logAndReport(ctx, err)
+		errs = append(errs, err)
+	}
+
+	// Hooks are stopped with a fresh context, since ctx may be the reason
+	// we're shutting down.
+	stopCtx := context.WithoutCancel(ctx)
+	if err := l.Stop(stopCtx); err != nil {
+		errs = append(errs, err.(*reporter.MultiError).Errors...)
+	}
+
+	if len(errs) > 0 {
+		return &reporter.MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// drain fails readiness, waits for DrainDelay, and gracefully shuts down srv.
+func (l *Lifecycle) drain(ctx context.Context, srv *http.Server, errs *[]error) {
+	if l.Health != nil {
+		l.Health.ShutDown()
+	}
+
+	if l.DrainDelay > 0 {
+		logger.Info(ctx, "Draining", "delay", l.DrainDelay)
+		time.Sleep(l.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Error from closing listeners, or context timeout:
+		err = fmt.Errorf("svc: HTTP server Shutdown: %v", err)
+		logAndReport(ctx, err)
+		*errs = append(*errs, err)
+	}
+}
+
+// sortHooks returns the indexes of the hooks sorted so that every hook comes
+// after the hooks it depends on, preserving the original order otherwise.
+func sortHooks(hooks []Hook) ([]int, error) {
+	byName := make(map[string]int, len(hooks))
+	for i, h := range hooks {
+		if h.Name != "" {
+			byName[h.Name] = i
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(hooks))
+	sorted := make([]int, 0, len(hooks))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("svc: hook %s has a circular dependency", hooks[i].Name)
+		case visited:
+			return nil
+		}
+
+		state[i] = visiting
+		for _, dep := range hooks[i].DependsOn {
+			j, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("svc: hook %s depends on unknown hook %s", hooks[i].Name, dep)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		sorted = append(sorted, i)
+		return nil
+	}
+
+	for i := range hooks {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// logAndReport logs err to the logger in ctx, and reports it to the reporter
+// in ctx, if there is one.
+func logAndReport(ctx context.Context, err error) {
+	logger.Error(ctx, err.Error())
+	if _, ok := reporter.FromContext(ctx); ok {
+		reporter.Report(ctx, err)
+	}
+}
@@ -0,0 +1,191 @@
+package svc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/svc"
+)
+
+func TestLifecycle(t *testing.T) {
+	var calls []string
+	hook := func(name string, deps ...string) svc.Hook {
+		return svc.Hook{
+			Name:      name,
+			DependsOn: deps,
+			Start: func(ctx context.Context) error {
+				calls = append(calls, "start "+name)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				calls = append(calls, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	l := svc.NewLifecycle()
+	l.Append(hook("worker", "db", "redis"))
+	l.Append(hook("db"))
+	l.Append(hook("redis"))
+
+	ctx := context.Background()
+	if err := l.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start db",
+		"start redis",
+		"start worker",
+		"stop worker",
+		"stop redis",
+		"stop db",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got %v; expected %v", calls, want)
+	}
+}
+
+func TestLifecycle_StartError(t *testing.T) {
+	var stopped bool
+
+	l := svc.NewLifecycle()
+	l.Append(svc.Hook{
+		Name: "db",
+		Stop: func(ctx context.Context) error { stopped = true; return nil },
+	})
+	l.Append(svc.Hook{
+		Name:  "worker",
+		Start: func(ctx context.Context) error { return errors.New("boom") },
+	})
+
+	err := l.Start(context.Background())
+	if got, want := err.Error(), "svc: starting worker: boom"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+	if !stopped {
+		t.Error("expected started hooks to be stopped")
+	}
+
+	l.Append(svc.Hook{Name: "cycle", DependsOn: []string{"cycle"}})
+	if err := l.Start(context.Background()); err == nil {
+		t.Error("expected an error for a circular dependency")
+	}
+}
+
+func TestLifecycle_StopTimeout(t *testing.T) {
+	l := svc.NewLifecycle()
+	l.Append(svc.Hook{
+		Name:        "stuck",
+		Stop:        func(ctx context.Context) error { select {} },
+		StopTimeout: 10 * time.Millisecond,
+	})
+	l.Append(svc.Hook{
+		Name: "failing",
+		Stop: func(ctx context.Context) error { return errors.New("boom") },
+	})
+
+	ctx := context.Background()
+	l.Start(ctx)
+
+	err := l.Stop(ctx)
+	if got, want := err.Error(), "svc: stopping failing: boom, svc: stopping stuck: context deadline exceeded"; got != want {
+		t.Errorf("got %s; expected %s", got, want)
+	}
+}
+
+func TestLifecycle_RunServer(t *testing.T) {
+	health := svc.NewHealth()
+
+	var stopped bool
+	l := svc.NewLifecycle()
+	l.Health = health
+	l.Append(svc.Hook{
+		Name: "worker",
+		Stop: func(ctx context.Context) error { stopped = true; return nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := svc.NewServer(http.NotFoundHandler(), svc.WithPort("0"))
+
+	errCh := make(chan error)
+	go func() { errCh <- l.RunServer(ctx, srv) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if !stopped {
+		t.Error("expected hooks to be stopped")
+	}
+	if r := health.Readiness(context.Background()); !r.ShuttingDown {
+		t.Error("expected readiness to fail once shutdown began")
+	}
+}
+
+func TestLifecycle_RunServerStandardHandler(t *testing.T) {
+	l := svc.NewLifecycle()
+	l.Health = svc.NewHealth()
+	l.DrainDelay = 200 * time.Millisecond
+
+	h := svc.NewStandardHandler(svc.HandlerOpts{
+		Router: httpx.NewRouter(),
+		Health: l.Health,
+	})
+	ready := func() int {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("GET", svc.ReadinessPath, nil))
+		return resp.Code
+	}
+	if got, want := ready(), http.StatusOK; got != want {
+		t.Fatalf("got %d; expected %d", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := svc.NewServer(h, svc.WithPort("0"))
+
+	errCh := make(chan error)
+	go func() { errCh <- l.RunServer(ctx, srv) }()
+	cancel()
+
+	// Readiness fails while the server drains, before it stops.
+	deadline := time.Now().Add(l.DrainDelay)
+	for ready() != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("expected readiness to fail while draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLifecycle_RunServerListenError(t *testing.T) {
+	l := svc.NewLifecycle()
+	l.Health = nil
+
+	srv := svc.NewServer(http.NotFoundHandler(), svc.WithPort("-1"))
+
+	err := l.RunServer(context.Background(), srv)
+	if err == nil || !strings.Contains(err.Error(), "ListenAndServe") {
+		t.Errorf("got %v; expected a listen error", err)
+	}
+}
@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	dd_ext "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// otelTracerName is the instrumentation name used for spans created by this
+// package.
+const otelTracerName = "github.com/remind101/pkg/tracing/contrib/aws"
+
+var (
+	// Starts an OpenTelemetry span and adds it to the request context.
+	OtelStartHandler = request.NamedHandler{
+		Name: "otel.Start",
+		Fn: func(r *request.Request) {
+			ctx, _ := otel.Tracer(otelTracerName).Start(r.Context(), "client.request",
+				trace.WithSpanKind(trace.SpanKindClient))
+			r.SetContext(ctx)
+		},
+	}
+
+	// Adds information about the request to the span.
+	OtelRequestInfoHandler = request.NamedHandler{
+		Name: "otel.RequestInfo",
+		Fn: func(r *request.Request) {
+			span := trace.SpanFromContext(r.Context())
+			span.SetAttributes(
+				attribute.String("service.name", fmt.Sprintf("aws.%s", r.ClientInfo.ServiceName)),
+				attribute.String("resource.name", r.Operation.Name),
+				attribute.String("http.method", r.Operation.HTTPMethod),
+				attribute.String("http.url", r.ClientInfo.Endpoint+r.Operation.HTTPPath),
+				attribute.String("out.host", r.ClientInfo.Endpoint),
+				attribute.String("aws.operation", r.Operation.Name),
+			)
+		},
+	}
+
+	// Finishes the span.
+	OtelFinishHandler = request.NamedHandler{
+		Name: "otel.Finish",
+		Fn: func(r *request.Request) {
+			span := trace.SpanFromContext(r.Context())
+			span.SetAttributes(attribute.Int("aws.retry_count", r.RetryCount))
+
+			if r.HTTPResponse != nil {
+				span.SetAttributes(attribute.Int("http.status_code", r.HTTPResponse.StatusCode))
+			}
+
+			if r.Error != nil {
+				otelSetError(span, r.Error)
+			}
+
+			span.End()
+		},
+	}
+
+	// OtelDynamoDBInfoHandler adds extra information to the span for
+	// requests to DynamoDB.
+	OtelDynamoDBInfoHandler = request.NamedHandler{
+		Name: "otel.DynamoDB",
+		Fn: func(r *request.Request) {
+			// Check if this request is for the DynamoDB API.
+			if r.ClientInfo.ServiceName != dynamodb.ServiceName {
+				return
+			}
+
+			tableName := dynamoDBTableName(r)
+			if tableName == nil {
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Operation.Name)
+			span.SetAttributes(
+				attribute.String("span.type", "db"),
+				attribute.String("resource.name", *tableName),
+				attribute.String("aws.dynamodb.table", *tableName),
+			)
+		},
+	}
+)
+
+// WithOtelTracing adds the necessary request handlers to an AWS
+// session.Session object to enable tracing with OpenTelemetry.
+func WithOtelTracing(s *session.Session) {
+	s.Handlers.Send.PushFrontNamed(OtelDynamoDBInfoHandler)
+	s.Handlers.Send.PushFrontNamed(OtelRequestInfoHandler)
+	s.Handlers.Send.PushFrontNamed(OtelStartHandler)
+
+	s.Handlers.Complete.PushBackNamed(OtelFinishHandler)
+}
+
+// OtelOption is a hook for adding span attributes.
+type OtelOption func(trace.Span, *request.Request, error)
+
+// OtelTracer is the OpenTelemetry counterpart of Tracer.
+type OtelTracer struct {
+	Opts []OtelOption
+}
+
+// NewOtel returns a new AWS request tracer with default tagging.
+func NewOtel(opts ...OtelOption) *OtelTracer {
+	opts = append([]OtelOption{otelDefaultTags}, opts...)
+	return &OtelTracer{opts}
+}
+
+// Send wraps the aws request with an OpenTelemetry span.
+//
+// req, output := dynamoClient.PutItemRequest(input)
+// err := t.Send(ctx, req)
+func (t *OtelTracer) Send(ctx context.Context, r *request.Request, opts ...OtelOption) error {
+	ctx, span := otel.Tracer(otelTracerName).Start(ctx, "client.request", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+	err := r.Send()
+
+	opts = append(t.Opts, opts...)
+	for _, fn := range opts {
+		fn(span, r, err)
+	}
+
+	return err
+}
+
+func otelDefaultTags(span trace.Span, r *request.Request, err error) {
+	span.SetAttributes(
+		attribute.String("resource.name", r.Operation.Name),
+		attribute.String("http.method", r.Operation.HTTPMethod),
+		attribute.String("http.url", r.ClientInfo.Endpoint+r.Operation.HTTPPath),
+		attribute.String("out.host", r.ClientInfo.Endpoint),
+		attribute.String("aws.operation", r.Operation.Name),
+		attribute.Int("aws.retry_count", r.RetryCount),
+	)
+
+	if r.HTTPResponse != nil {
+		span.SetAttributes(attribute.Int("http.status_code", r.HTTPResponse.StatusCode))
+	}
+
+	if err != nil {
+		otelSetError(span, err)
+	}
+}
+
+func otelSetError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if _, ok := err.(fmt.Formatter); ok {
+		span.SetAttributes(attribute.String(dd_ext.ErrorStack, fmt.Sprintf("%+v", err)))
+	}
+	if err, ok := err.(awserr.Error); ok {
+		span.SetAttributes(attribute.String("aws.err.code", err.Code()))
+	}
+}
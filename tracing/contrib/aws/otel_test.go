@@ -0,0 +1,72 @@
+package aws_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/mock"
+	awsot "github.com/remind101/pkg/tracing/contrib/aws"
+	"github.com/remind101/pkg/tracing/oteltest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestWithOtelTracing(t *testing.T) {
+	exporter, restore := oteltest.Install()
+	defer restore()
+
+	sess := mock.Session.Copy()
+	awsot.WithOtelTracing(sess)
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+
+	client := mock.NewMockClient(&aws.Config{Region: aws.String("us-west-2")})
+	client.Handlers = sess.Handlers.Copy()
+
+	r := client.NewRequest(&request.Operation{
+		Name:       "object.get",
+		HTTPMethod: "GET",
+		HTTPPath:   "/foobar",
+	}, nil, nil)
+
+	r.SetContext(ctx)
+	if err := r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 finished spans, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if got, want := span.Name, "client.request"; got != want {
+		t.Errorf("got: %+v; expected %+v", got, want)
+	}
+	if got, want := span.Parent.SpanID(), root.SpanContext().SpanID(); got != want {
+		t.Errorf("parent => %s; expected %s", got, want)
+	}
+
+	for k, v := range map[string]string{
+		"service.name":     "aws.Mock",
+		"resource.name":    "object.get",
+		"http.method":      "GET",
+		"http.url":         client.Endpoint + "/foobar",
+		"http.status_code": "200",
+		"out.host":         client.Endpoint,
+		"aws.operation":    "object.get",
+		"aws.retry_count":  "0",
+	} {
+		if got, want := oteltest.Attribute(span, k).Emit(), v; got != want {
+			t.Errorf("attribute %s: %+v; expected %+v", k, got, want)
+		}
+	}
+	for _, k := range []string{"http.status_code", "aws.retry_count"} {
+		if got, want := oteltest.Attribute(span, k).Type(), attribute.INT64; got != want {
+			t.Errorf("attribute %s: %v; expected %v", k, got, want)
+		}
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"github.com/go-redis/redis"
 	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func WrapClient(ctx context.Context, c *redis.Client, serviceName string) *redis.Client {
@@ -44,3 +48,40 @@ func NewClient(connstr string) *redis.Client {
 	}
 	return redis.NewClient(opt)
 }
+
+// WrapClientOtel is the OpenTelemetry counterpart of WrapClient. Commands are
+// traced as children of the span in ctx, if any, using the global
+// TracerProvider.
+func WrapClientOtel(ctx context.Context, c *redis.Client, serviceName string) *redis.Client {
+	if ctx == nil {
+		return c
+	}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return c
+	}
+
+	copy := c.WithContext(c.Context())
+	copy.WrapProcess(func(oldProcess func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			_, span := otel.Tracer(otelTracerName).Start(ctx, "redis.command", trace.WithSpanKind(trace.SpanKindClient))
+			span.SetAttributes(
+				attribute.String(ext.ServiceName, serviceName),
+				attribute.String(ext.SpanType, "cache"),
+				attribute.String(ext.ResourceName, cmd.Name()),
+			)
+			defer span.End()
+
+			err := oldProcess(cmd)
+			if err != nil && err != redis.Nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	})
+	return copy
+}
+
+// otelTracerName is the instrumentation name used for spans created by this
+// package.
+const otelTracerName = "github.com/remind101/pkg/tracing/contrib/go-redis/redis"
@@ -43,3 +43,24 @@ func (p *SNSCarrierPropagator) ForeachKey(handler func(key, val string) error) e
 	// When should this return an error?
 	return nil
 }
+
+// Get returns the value for key. Together with Set and Keys, it implements the
+// OpenTelemetry propagation.TextMapCarrier interface.
+func (p *SNSCarrierPropagator) Get(key string) string {
+	v, ok := p.publishInput.MessageAttributes[addPrefix(key)]
+	if !ok || v.DataType == nil || *v.DataType != "String" || v.StringValue == nil {
+		return ""
+	}
+	return *v.StringValue
+}
+
+// Keys lists the keys stored in this carrier.
+func (p *SNSCarrierPropagator) Keys() []string {
+	var keys []string
+	for k, v := range p.publishInput.MessageAttributes {
+		if v.DataType != nil && *v.DataType == "String" && hasPrefix(k) {
+			keys = append(keys, removePrefix(k))
+		}
+	}
+	return keys
+}
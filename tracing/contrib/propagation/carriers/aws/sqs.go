@@ -45,3 +45,24 @@ func (p *SQSCarrierPropagator) ForeachKey(handler func(key, val string) error) e
 	// When should this return an error?
 	return nil
 }
+
+// Get returns the value for key. Together with Set and Keys, it implements the
+// OpenTelemetry propagation.TextMapCarrier interface.
+func (p *SQSCarrierPropagator) Get(key string) string {
+	v, ok := p.msg.MessageAttributes[addPrefix(key)]
+	if !ok || v.DataType == nil || *v.DataType != "String" || v.StringValue == nil {
+		return ""
+	}
+	return *v.StringValue
+}
+
+// Keys lists the keys stored in this carrier.
+func (p *SQSCarrierPropagator) Keys() []string {
+	var keys []string
+	for k, v := range p.msg.MessageAttributes {
+		if v.DataType != nil && *v.DataType == "String" && hasPrefix(k) {
+			keys = append(keys, removePrefix(k))
+		}
+	}
+	return keys
+}
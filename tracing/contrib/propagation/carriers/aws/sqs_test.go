@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/service/sns"
@@ -8,6 +9,9 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/remind101/pkg/tracing/oteltest"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestSQSCarrier(t *testing.T) {
@@ -69,3 +73,22 @@ func TestSNSCarrier(t *testing.T) {
 		return
 	}
 }
+
+func TestSQSCarrierOtel(t *testing.T) {
+	tp, _ := oteltest.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test span")
+	defer span.End()
+
+	m := &sqs.Message{}
+	propagator := propagation.TraceContext{}
+	propagator.Inject(ctx, SQSCarrier(m))
+
+	if _, ok := m.MessageAttributes["ot-carrier-traceparent"]; !ok {
+		t.Fatal("expected traceparent message attribute")
+	}
+
+	sc := trace.SpanContextFromContext(propagator.Extract(context.Background(), SQSCarrier(m)))
+	if got, want := sc.SpanID(), span.SpanContext().SpanID(); got != want {
+		t.Errorf("span id => %s; want %s", got, want)
+	}
+}
@@ -1,7 +1,14 @@
 package redis
 
+import (
+	"github.com/remind101/pkg/redact"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type dialConfig struct {
-	serviceName string
+	serviceName    string
+	tracerProvider trace.TracerProvider
+	redaction      *redact.Policy
 }
 
 // DialOption represents an option that can be passed to Dial.
@@ -9,6 +16,7 @@ type DialOption func(*dialConfig)
 
 func defaults(cfg *dialConfig) {
 	cfg.serviceName = "redis.conn"
+	cfg.redaction = redact.Default
 }
 
 // WithServiceName sets the given service name for the dialled connection.
@@ -17,3 +25,19 @@ func WithServiceName(name string) DialOption {
 		cfg.serviceName = name
 	}
 }
+
+// WithTracerProvider traces commands with OpenTelemetry, using the given
+// TracerProvider, instead of opentracing.
+func WithTracerProvider(tp trace.TracerProvider) DialOption {
+	return func(cfg *dialConfig) {
+		cfg.tracerProvider = tp
+	}
+}
+
+// WithRedaction sets the policy used to redact the arguments of the
+// redis.command tag. The default is redact.Default.
+func WithRedaction(p *redact.Policy) DialOption {
+	return func(cfg *dialConfig) {
+		cfg.redaction = p
+	}
+}
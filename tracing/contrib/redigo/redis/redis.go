@@ -1,5 +1,6 @@
 // COPIED from https://github.com/DataDog/dd-trace-go/tree/master/contrib/garyburd/redigo
-// and modified to use opentracing.
+// and modified to use opentracing, or OpenTelemetry when dialled with
+// WithTracerProvider.
 //
 // TODO: Remove once dd-trace-go switches their contrib packages to use opentracing.
 package redis
@@ -11,9 +12,14 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/opentracing/opentracing-go"
+	"github.com/remind101/pkg/redact"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
 
@@ -75,6 +81,10 @@ func (tc Conn) Do(commandName string, args ...interface{}) (reply interface{}, e
 		ctx = context.Background()
 	}
 
+	if tc.config.tracerProvider != nil {
+		return tc.doOtel(ctx, commandName, args...)
+	}
+
 	span := tc.newChildSpan(ctx)
 	defer func() {
 		if err != nil {
@@ -84,21 +94,71 @@ func (tc Conn) Do(commandName string, args ...interface{}) (reply interface{}, e
 	}()
 
 	span.SetTag("redis.args_length", strconv.Itoa(len(args)))
+	span.SetTag(ext.ResourceName, resourceName(commandName))
+	span.SetTag("redis.command", commandString(tc.config.redaction, commandName, args))
+	return tc.Conn.Do(commandName, args...)
+}
+
+// doOtel is the OpenTelemetry counterpart of Do.
+func (tc Conn) doOtel(ctx context.Context, commandName string, args ...interface{}) (reply interface{}, err error) {
+	p := tc.params
+	_, span := p.config.tracerProvider.Tracer(otelTracerName).Start(ctx, "redis.command",
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
+	span.SetAttributes(
+		attribute.String(ext.ServiceName, p.config.serviceName),
+		attribute.String(ext.SpanType, "cache"),
+		attribute.String("out.network", p.network),
+		attribute.String("out.port", p.port),
+		attribute.String("out.host", p.host),
+		attribute.String("redis.args_length", strconv.Itoa(len(args))),
+		attribute.String(ext.ResourceName, resourceName(commandName)),
+		attribute.String("redis.command", commandString(tc.config.redaction, commandName, args)),
+	)
+	return tc.Conn.Do(commandName, args...)
+}
+
+// otelTracerName is the instrumentation name used for spans created by this
+// package.
+const otelTracerName = "github.com/remind101/pkg/tracing/contrib/redigo/redis"
+
+// resourceName returns the resource name for a command.
+func resourceName(commandName string) string {
 	if len(commandName) > 0 {
-		span.SetTag(ext.ResourceName, commandName)
-	} else {
-		// When the command argument to the Do method is "", then the Do method will flush the output buffer
-		// See https://pkg.go.dev/github.com/gomodule/redigo/redis#hdr-Pipelining
-		span.SetTag(ext.ResourceName, "conn.flush")
+		return commandName
 	}
+	// When the command argument to the Do method is "", then the Do method will flush the output buffer
+	// See https://pkg.go.dev/github.com/gomodule/redigo/redis#hdr-Pipelining
+	return "conn.flush"
+}
+
+// commandString formats a command and its arguments for the redis.command tag.
+// The arguments are scrubbed with p, and those following a sensitive argument,
+// like the value of a "password" hash field, are masked. So are all the
+// arguments of AUTH.
+func commandString(p *redact.Policy, commandName string, args []interface{}) string {
 	var b bytes.Buffer
 	b.WriteString(commandName)
+	auth := p != nil && strings.EqualFold(commandName, "AUTH")
+	mask := false
 	for _, arg := range args {
 		b.WriteString(" ")
+		if auth || mask {
+			b.WriteString(redact.Mask)
+			mask = false
+			continue
+		}
 		switch arg := arg.(type) {
 		case string:
-			b.WriteString(arg)
+			b.WriteString(p.Scrub(arg))
+			mask = p.IsSensitive(arg)
 		case int:
 			b.WriteString(strconv.Itoa(arg))
 		case int32:
@@ -106,11 +166,10 @@ func (tc Conn) Do(commandName string, args ...interface{}) (reply interface{}, e
 		case int64:
 			b.WriteString(strconv.FormatInt(arg, 10))
 		case fmt.Stringer:
-			b.WriteString(arg.String())
+			b.WriteString(p.Scrub(arg.String()))
 		}
 	}
-	span.SetTag("redis.command", b.String())
-	return tc.Conn.Do(commandName, args...)
+	return b.String()
 }
 
 // newChildSpan creates a span inheriting from the given context. It adds to the span useful metadata about the traced Redis connection
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/remind101/pkg/redact"
+)
+
+func TestCommandString(t *testing.T) {
+	tests := []struct {
+		commandName string
+		args        []interface{}
+		out         string
+	}{
+		{"GET", []interface{}{"user:1"}, "GET user:1"},
+		{"SET", []interface{}{"user:1", 42}, "SET user:1 42"},
+		{"AUTH", []interface{}{"admin", "hunter2"}, "AUTH [REDACTED] [REDACTED]"},
+		{"HSET", []interface{}{"user:1", "password", "hunter2", "name", "jane"}, "HSET user:1 password [REDACTED] name jane"},
+		{"SET", []interface{}{"email:1", "jane@example.com"}, "SET email:1 [REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		if got, want := commandString(redact.Default, tt.commandName, tt.args), tt.out; got != want {
+			t.Errorf("commandString(%q, %v) => %q; want %q", tt.commandName, tt.args, got, want)
+		}
+	}
+
+	if got, want := commandString(nil, "AUTH", []interface{}{"hunter2"}), "AUTH hunter2"; got != want {
+		t.Errorf("commandString without a policy => %q; want %q", got, want)
+	}
+}
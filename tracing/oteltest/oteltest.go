@@ -0,0 +1,64 @@
+// Package oteltest provides an in-memory OpenTelemetry exporter for testing
+// code instrumented with OpenTelemetry.
+//
+// Usage:
+//
+//	func TestSomething(t *testing.T) {
+//		exporter, restore := oteltest.Install()
+//		defer restore()
+//
+//		// ... exercise code that creates spans
+//
+//		spans := exporter.GetSpans()
+//		oteltest.Attribute(spans[0], "resource.name").AsString()
+//	}
+package oteltest
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewTracerProvider returns a TracerProvider that synchronously records every
+// finished span in the returned exporter.
+func NewTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	return tp, exporter
+}
+
+// Install sets a recording TracerProvider as the global TracerProvider, and a
+// W3C trace context and baggage propagator as the global TextMapPropagator.
+// The returned function restores the previous ones.
+func Install() (*tracetest.InMemoryExporter, func()) {
+	oldTP, oldPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+
+	tp, exporter := NewTracerProvider()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return exporter, func() {
+		otel.SetTracerProvider(oldTP)
+		otel.SetTextMapPropagator(oldPropagator)
+	}
+}
+
+// Attribute returns the value of the attribute with the given key on a
+// recorded span. The zero Value is returned if the attribute is not set.
+func Attribute(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,143 @@
+package tracecontext
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Limits defined by the W3C Baggage specification.
+const (
+	MaxBaggageMembers = 180
+	MaxBaggageBytes   = 8192
+)
+
+// Member is a single baggage list member.
+type Member struct {
+	Key   string
+	Value string
+
+	// Properties holds the raw, semicolon separated, metadata of the
+	// member, if any.
+	Properties string
+}
+
+// Baggage is an ordered list of baggage members.
+type Baggage []Member
+
+// ParseBaggage parses the value of a baggage header. Members that are
+// malformed are skipped.
+func ParseBaggage(s string) Baggage {
+	var b Baggage
+
+	for _, m := range strings.Split(s, ",") {
+		if len(b) >= MaxBaggageMembers {
+			break
+		}
+
+		var props string
+		if i := strings.IndexByte(m, ';'); i >= 0 {
+			m, props = m[:i], strings.TrimSpace(m[i+1:])
+		}
+
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			continue
+		}
+
+		k := strings.TrimSpace(m[:i])
+		if !validKey(k) {
+			continue
+		}
+
+		v, err := url.PathUnescape(strings.TrimSpace(m[i+1:]))
+		if err != nil {
+			continue
+		}
+
+		b = append(b, Member{Key: k, Value: v, Properties: props})
+	}
+
+	return b
+}
+
+// Get returns the value of the member with the given key.
+func (b Baggage) Get(key string) (string, bool) {
+	for _, m := range b {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns a copy of b with the given member added, or replaced if a member
+// with the same key already exists.
+func (b Baggage) Set(key, value string) Baggage {
+	n := make(Baggage, 0, len(b)+1)
+	for _, m := range b {
+		if m.Key != key {
+			n = append(n, m)
+		}
+	}
+	return append(n, Member{Key: key, Value: value})
+}
+
+// String encodes b as a baggage header value. Members with invalid keys are
+// skipped, and members that would take the header over the size limits are
+// dropped.
+func (b Baggage) String() string {
+	var parts []string
+	size := 0
+
+	for _, m := range b {
+		if !validKey(m.Key) {
+			continue
+		}
+		if len(parts) >= MaxBaggageMembers {
+			break
+		}
+
+		s := m.Key + "=" + escapeValue(m.Value)
+		if m.Properties != "" {
+			s += ";" + m.Properties
+		}
+
+		if size+len(s)+1 > MaxBaggageBytes {
+			continue
+		}
+		size += len(s) + 1
+		parts = append(parts, s)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// validKey reports whether k is a valid token as defined by RFC 7230.
+func validKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeValue percent encodes the characters that are not allowed in baggage
+// values.
+func escapeValue(v string) string {
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == ',' || c == ';' || c == '\\' || c == '%' {
+			sb.WriteByte('%')
+			sb.WriteByte("0123456789ABCDEF"[c>>4])
+			sb.WriteByte("0123456789ABCDEF"[c&0xf])
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
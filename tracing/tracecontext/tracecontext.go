@@ -0,0 +1,310 @@
+// Package tracecontext implements propagation of W3C Trace Context
+// (https://www.w3.org/TR/trace-context/) and W3C Baggage
+// (https://www.w3.org/TR/baggage/) over http headers.
+//
+// Unlike the opentracing HTTPHeaders format, which depends on the global
+// tracer, these headers are understood by every major tracing vendor, which
+// allows services using different tracers to join the same trace.
+//
+// Usage:
+//
+//	// Server side, usually done by middleware.ExtractTraceContext.
+//	ctx = tracecontext.WithSpanContext(ctx, tracecontext.Extract(r.Header))
+//
+//	// Client side.
+//	tracecontext.Inject(ctx, req.Header)
+package tracecontext
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header names defined by the W3C specifications.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+	BaggageHeader     = "baggage"
+)
+
+// ErrInvalidTraceParent is returned when a traceparent header can't be parsed.
+var ErrInvalidTraceParent = errors.New("tracecontext: invalid traceparent")
+
+// TraceID is a 16 byte trace identifier.
+type TraceID [16]byte
+
+// IsValid reports whether the trace id is not all zeroes.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex encoding of the trace id.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID is an 8 byte span identifier.
+type SpanID [8]byte
+
+// IsValid reports whether the span id is not all zeroes.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// String returns the lowercase hex encoding of the span id.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// TraceFlags are the flags propagated in the traceparent header.
+type TraceFlags byte
+
+// FlagSampled indicates that the caller may have recorded the trace.
+const FlagSampled TraceFlags = 0x01
+
+// TraceParent is the parsed value of a traceparent header.
+type TraceParent struct {
+	TraceID  TraceID
+	ParentID SpanID
+	Flags    TraceFlags
+}
+
+// ParseTraceParent parses the value of a traceparent header. Versions higher
+// than 00 are parsed according to the forward compatibility rules of the
+// specification.
+func ParseTraceParent(s string) (TraceParent, error) {
+	var tp TraceParent
+
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tp, ErrInvalidTraceParent
+	}
+
+	version, ok := decodeHex(s[0:2])
+	if !ok || version[0] == 0xff {
+		return tp, ErrInvalidTraceParent
+	}
+	if version[0] == 0 && len(s) != 55 {
+		return tp, ErrInvalidTraceParent
+	}
+	if len(s) > 55 && s[55] != '-' {
+		return tp, ErrInvalidTraceParent
+	}
+
+	traceID, ok := decodeHex(s[3:35])
+	if !ok {
+		return tp, ErrInvalidTraceParent
+	}
+	parentID, ok := decodeHex(s[36:52])
+	if !ok {
+		return tp, ErrInvalidTraceParent
+	}
+	flags, ok := decodeHex(s[53:55])
+	if !ok {
+		return tp, ErrInvalidTraceParent
+	}
+
+	copy(tp.TraceID[:], traceID)
+	copy(tp.ParentID[:], parentID)
+	tp.Flags = TraceFlags(flags[0])
+
+	if !tp.IsValid() {
+		return TraceParent{}, ErrInvalidTraceParent
+	}
+	return tp, nil
+}
+
+// decodeHex decodes a lowercase hex string. Uppercase is not allowed by the
+// specification.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// IsValid reports whether both the trace id and parent id are set.
+func (tp TraceParent) IsValid() bool {
+	return tp.TraceID.IsValid() && tp.ParentID.IsValid()
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tp TraceParent) Sampled() bool {
+	return tp.Flags&FlagSampled != 0
+}
+
+// String formats tp as a version 00 traceparent header value.
+func (tp TraceParent) String() string {
+	return "00-" + tp.TraceID.String() + "-" + tp.ParentID.String() + "-" + hex.EncodeToString([]byte{byte(tp.Flags)})
+}
+
+// SpanContext is the trace context that is propagated with a request.
+type SpanContext struct {
+	TraceParent
+
+	// TraceState is the opaque, vendor specific tracestate header value.
+	TraceState string
+
+	// Baggage holds user defined key value pairs.
+	Baggage Baggage
+}
+
+// Extract extracts a SpanContext from http headers. An invalid traceparent is
+// ignored, along with the tracestate, but baggage is always extracted.
+func Extract(h http.Header) SpanContext {
+	var sc SpanContext
+
+	if tp, err := ParseTraceParent(h.Get(TraceParentHeader)); err == nil {
+		sc.TraceParent = tp
+		sc.TraceState = strings.Join(h.Values(TraceStateHeader), ",")
+	}
+
+	sc.Baggage = ParseBaggage(strings.Join(h.Values(BaggageHeader), ","))
+
+	return sc
+}
+
+// Inject adds the traceparent, tracestate and baggage headers for an outgoing
+// request made within ctx, as returned by Current.
+//
+// Headers that are already present, for instance because the tracer
+// injected its own traceparent, are left untouched.
+func Inject(ctx context.Context, h http.Header) {
+	sc := Current(ctx)
+
+	if sc.IsValid() && h.Get(TraceParentHeader) == "" {
+		h.Set(TraceParentHeader, sc.TraceParent.String())
+		if sc.TraceState != "" && h.Get(TraceStateHeader) == "" {
+			h.Set(TraceStateHeader, sc.TraceState)
+		}
+	}
+
+	if len(sc.Baggage) > 0 && h.Get(BaggageHeader) == "" {
+		if b := sc.Baggage.String(); b != "" {
+			h.Set(BaggageHeader, b)
+		}
+	}
+}
+
+// Current returns the trace context of the active span in ctx. When ctx holds
+// an OpenTelemetry span, or an opentracing span whose context exposes numeric
+// ids, like the Datadog tracer's, the span is the parent. Otherwise the
+// incoming trace context in ctx is returned unchanged.
+//
+// The incoming trace context is also returned unchanged when the active span
+// belongs to another trace, for instance because the tracer didn't join the
+// incoming trace, since its span id doesn't exist in the incoming trace.
+func Current(ctx context.Context) SpanContext {
+	sc, _ := FromContext(ctx)
+
+	traceID, spanID, ok := Active(ctx)
+	if !ok {
+		return sc
+	}
+
+	if !sc.TraceID.IsValid() {
+		sc.TraceID = traceID
+		sc.Flags = FlagSampled
+		if otelSC := trace.SpanContextFromContext(ctx); otelSC.IsValid() {
+			sc.Flags = TraceFlags(otelSC.TraceFlags())
+		}
+	} else if !sameTrace(sc.TraceID, traceID) {
+		return sc
+	}
+	sc.ParentID = spanID
+
+	return sc
+}
+
+// sameTrace reports whether the trace id of the active span is the incoming
+// one. Tracers with 64 bit ids, like the Datadog tracer, join W3C traces with
+// the lower 64 bits of the trace id.
+func sameTrace(incoming, active TraceID) bool {
+	if incoming == active {
+		return true
+	}
+	var upper [8]byte
+	return [8]byte(active[:8]) == upper && [8]byte(active[8:]) == [8]byte(incoming[8:])
+}
+
+// Active returns the trace and span ids of the active OpenTelemetry span in
+// ctx, or of the active opentracing span whose context exposes numeric ids,
+// like the Datadog tracer's. Unlike Current, the incoming trace context in ctx
+// isn't used.
+func Active(ctx context.Context) (TraceID, SpanID, bool) {
+	if otelSC := trace.SpanContextFromContext(ctx); otelSC.IsValid() {
+		return TraceID(otelSC.TraceID()), SpanID(otelSC.SpanID()), true
+	}
+
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return TraceID{}, SpanID{}, false
+	}
+	ids, ok := span.Context().(spanIDs)
+	if !ok || ids.SpanID() == 0 {
+		return TraceID{}, SpanID{}, false
+	}
+
+	var traceID TraceID
+	var spanID SpanID
+	if w3c, ok := ids.(traceID128); ok {
+		traceID = w3c.TraceID128Bytes()
+	} else {
+		binary.BigEndian.PutUint64(traceID[8:], ids.TraceID())
+	}
+	binary.BigEndian.PutUint64(spanID[:], ids.SpanID())
+	return traceID, spanID, true
+}
+
+// spanIDs is implemented by span contexts that expose numeric ids, such as
+// the ones created by the Datadog tracer.
+type spanIDs interface {
+	TraceID() uint64
+	SpanID() uint64
+}
+
+// traceID128 is implemented by span contexts that carry a 128 bit trace id.
+type traceID128 interface {
+	TraceID128Bytes() [16]byte
+}
+
+// WithSpanContext inserts a SpanContext into the context.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// FromContext extracts a SpanContext from the context.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok
+}
+
+// WithBaggageItem returns a context whose SpanContext has the given baggage
+// item set, so that it's propagated to downstream services.
+func WithBaggageItem(ctx context.Context, key, value string) context.Context {
+	sc, _ := FromContext(ctx)
+	sc.Baggage = sc.Baggage.Set(key, value)
+	return WithSpanContext(ctx, sc)
+}
+
+// BaggageItem returns the value of a baggage item from the context.
+func BaggageItem(ctx context.Context, key string) string {
+	sc, _ := FromContext(ctx)
+	v, _ := sc.Baggage.Get(key)
+	return v
+}
+
+// key used to store context values from within this package.
+type key int
+
+const (
+	spanContextKey key = iota
+)
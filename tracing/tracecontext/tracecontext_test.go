@@ -0,0 +1,147 @@
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		tp, err := ParseTraceParent(tt.in)
+		if got, want := err == nil, tt.valid; got != want {
+			t.Errorf("ParseTraceParent(%q) => %v; want valid %v", tt.in, err, want)
+		}
+		if err == nil && len(tt.in) == 55 && tp.String() != tt.in {
+			t.Errorf("String() => %q; want %q", tp.String(), tt.in)
+		}
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	b := ParseBaggage("userId=alice, serverNode = DF%2028 ;prop=1,invalid,=novalue,is ok=no")
+
+	want := Baggage{
+		{Key: "userId", Value: "alice"},
+		{Key: "serverNode", Value: "DF 28", Properties: "prop=1"},
+	}
+	if !reflect.DeepEqual(b, want) {
+		t.Fatalf("ParseBaggage => %#v; want %#v", b, want)
+	}
+
+	b = b.Set("userId", "bob, jr")
+	if got, want := b.String(), "serverNode=DF%2028;prop=1,userId=bob%2C%20jr"; got != want {
+		t.Errorf("String() => %q; want %q", got, want)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	in := http.Header{}
+	in.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set("tracestate", "rojo=00f067aa0ba902b7")
+	in.Set("baggage", "userId=alice")
+
+	ctx := WithSpanContext(context.Background(), Extract(in))
+
+	// Without a span, the trace context is forwarded unchanged.
+	out := http.Header{}
+	Inject(ctx, out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Inject => %v; want %v", out, in)
+	}
+
+	// With a span in another trace, the trace context is forwarded
+	// unchanged, since the span doesn't exist in the incoming trace.
+	ctx = opentracing.ContextWithSpan(ctx, &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")})
+	out = http.Header{}
+	Inject(ctx, out)
+	if got, want := out.Get("traceparent"), in.Get("traceparent"); got != want {
+		t.Errorf("traceparent => %q; want %q", got, want)
+	}
+
+	// With a span that joined the trace, the span becomes the parent.
+	ctx = opentracing.ContextWithSpan(ctx, &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test"), traceID: 0xa3ce929d0e0e4736})
+	out = http.Header{}
+	Inject(ctx, out)
+	if got, want := out.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-000000000000002a-01"; got != want {
+		t.Errorf("traceparent => %q; want %q", got, want)
+	}
+
+	// Headers that are already set are not overwritten.
+	out = http.Header{"Traceparent": []string{"set"}}
+	Inject(ctx, out)
+	if got, want := out.Get("traceparent"), "set"; got != want {
+		t.Errorf("traceparent => %q; want %q", got, want)
+	}
+}
+
+func TestInjectNewTrace(t *testing.T) {
+	ctx := opentracing.ContextWithSpan(context.Background(), &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")})
+	ctx = WithBaggageItem(ctx, "tier", "gold")
+
+	out := http.Header{}
+	Inject(ctx, out)
+	if got, want := out.Get("traceparent"), "00-00000000000000000000000000000007-000000000000002a-01"; got != want {
+		t.Errorf("traceparent => %q; want %q", got, want)
+	}
+	if got, want := out.Get("baggage"), "tier=gold"; got != want {
+		t.Errorf("baggage => %q; want %q", got, want)
+	}
+}
+
+type fakeSpan struct {
+	opentracing.Span
+	traceID uint64
+}
+
+func (s *fakeSpan) Context() opentracing.SpanContext {
+	if s.traceID == 0 {
+		return fakeSpanContext{traceID: 7}
+	}
+	return fakeSpanContext{traceID: s.traceID}
+}
+
+type fakeSpanContext struct {
+	traceID uint64
+}
+
+func (fakeSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+func (c fakeSpanContext) TraceID() uint64                         { return c.traceID }
+func (fakeSpanContext) SpanID() uint64                            { return 42 }
+
+func TestActive(t *testing.T) {
+	if _, _, ok := Active(context.Background()); ok {
+		t.Error("expected no active span")
+	}
+
+	ctx := opentracing.ContextWithSpan(context.Background(), &fakeSpan{Span: opentracing.NoopTracer{}.StartSpan("test")})
+	traceID, spanID, ok := Active(ctx)
+	if !ok {
+		t.Fatal("expected an active span")
+	}
+	if got, want := traceID.String(), "00000000000000000000000000000007"; got != want {
+		t.Errorf("trace id => %q; want %q", got, want)
+	}
+	if got, want := spanID.String(), "000000000000002a"; got != want {
+		t.Errorf("span id => %q; want %q", got, want)
+	}
+}